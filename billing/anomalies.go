@@ -0,0 +1,54 @@
+package billing
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// GetAnomalies follows NextPageToken and returns the anomalies of all pages.
+func GetAnomalies(src AnomalySource, input *costexplorer.GetAnomaliesInput) ([]*costexplorer.Anomaly, error) {
+	in := *input
+	in.NextPageToken = nil
+
+	anomalies := []*costexplorer.Anomaly{}
+
+	for {
+		page, err := src.GetAnomalies(&in)
+		if err != nil {
+			return nil, err
+		}
+
+		anomalies = append(anomalies, page.Anomalies...)
+
+		if aws.StringValue(page.NextPageToken) == "" {
+			break
+		}
+		in.NextPageToken = page.NextPageToken
+	}
+
+	return anomalies, nil
+}
+
+// GetAnomalyMonitors follows NextPageToken and returns the monitors of all
+// pages.
+func GetAnomalyMonitors(src AnomalySource) ([]*costexplorer.AnomalyMonitor, error) {
+	input := &costexplorer.GetAnomalyMonitorsInput{}
+
+	monitors := []*costexplorer.AnomalyMonitor{}
+
+	for {
+		page, err := src.GetAnomalyMonitors(input)
+		if err != nil {
+			return nil, err
+		}
+
+		monitors = append(monitors, page.AnomalyMonitors...)
+
+		if aws.StringValue(page.NextPageToken) == "" {
+			break
+		}
+		input.NextPageToken = page.NextPageToken
+	}
+
+	return monitors, nil
+}
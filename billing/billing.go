@@ -0,0 +1,86 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/budgets"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/organizations"
+)
+
+// AccountsSource lists the accounts and organizational units of an
+// organization.
+type AccountsSource interface {
+	DescribeOrganization(*organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error)
+	ListAccounts(*organizations.ListAccountsInput) (*organizations.ListAccountsOutput, error)
+	ListRoots(*organizations.ListRootsInput) (*organizations.ListRootsOutput, error)
+	ListOrganizationalUnitsForParent(*organizations.ListOrganizationalUnitsForParentInput) (*organizations.ListOrganizationalUnitsForParentOutput, error)
+	ListAccountsForParent(*organizations.ListAccountsForParentInput) (*organizations.ListAccountsForParentOutput, error)
+	ListTagsForResource(*organizations.ListTagsForResourceInput) (*organizations.ListTagsForResourceOutput, error)
+}
+
+// CostSource returns actual costs and the dimension values, tags and cost
+// categories they can be grouped by.
+type CostSource interface {
+	GetCostAndUsage(*costexplorer.GetCostAndUsageInput) (*costexplorer.GetCostAndUsageOutput, error)
+	GetDimensionValues(*costexplorer.GetDimensionValuesInput) (*costexplorer.GetDimensionValuesOutput, error)
+	GetTags(*costexplorer.GetTagsInput) (*costexplorer.GetTagsOutput, error)
+	GetCostCategories(*costexplorer.GetCostCategoriesInput) (*costexplorer.GetCostCategoriesOutput, error)
+	ListCostCategoryDefinitions(*costexplorer.ListCostCategoryDefinitionsInput) (*costexplorer.ListCostCategoryDefinitionsOutput, error)
+}
+
+// ForecastSource returns forecasted costs.
+type ForecastSource interface {
+	GetCostForecast(*costexplorer.GetCostForecastInput) (*costexplorer.GetCostForecastOutput, error)
+}
+
+// AnomalySource returns the findings of Cost Anomaly Detection and takes
+// feedback on them.
+type AnomalySource interface {
+	GetAnomalies(*costexplorer.GetAnomaliesInput) (*costexplorer.GetAnomaliesOutput, error)
+	GetAnomalyMonitors(*costexplorer.GetAnomalyMonitorsInput) (*costexplorer.GetAnomalyMonitorsOutput, error)
+	ProvideAnomalyFeedback(*costexplorer.ProvideAnomalyFeedbackInput) (*costexplorer.ProvideAnomalyFeedbackOutput, error)
+}
+
+// BudgetSource returns and manages the budgets of an account.
+type BudgetSource interface {
+	DescribeBudgets(*budgets.DescribeBudgetsInput) (*budgets.DescribeBudgetsOutput, error)
+	CreateBudget(*budgets.CreateBudgetInput) (*budgets.CreateBudgetOutput, error)
+	UpdateBudget(*budgets.UpdateBudgetInput) (*budgets.UpdateBudgetOutput, error)
+	DeleteBudget(*budgets.DeleteBudgetInput) (*budgets.DeleteBudgetOutput, error)
+}
+
+// Backend bundles the sources the commands read billing data from.
+type Backend struct {
+	Accounts  AccountsSource
+	Costs     CostSource
+	Forecasts ForecastSource
+	Anomalies AnomalySource
+	Budgets   BudgetSource
+
+	// Clock tells the time reports are made at, defaults to time.Now.
+	Clock func() time.Time
+}
+
+// Now returns the time reports are made at.
+func (b *Backend) Now() time.Time {
+	if b.Clock != nil {
+		return b.Clock()
+	}
+
+	return time.Now()
+}
+
+// NewAWSBackend returns a Backend talking to the AWS APIs using the given session.
+func NewAWSBackend(sess *session.Session) *Backend {
+	costExplorerSvc := costexplorer.New(sess)
+
+	return &Backend{
+		Accounts:  organizations.New(sess),
+		Costs:     costExplorerSvc,
+		Forecasts: costExplorerSvc,
+		Anomalies: costExplorerSvc,
+		Budgets:   budgets.New(sess),
+	}
+}
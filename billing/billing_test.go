@@ -0,0 +1,360 @@
+package billing_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/budgets"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/organizations"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/billing/fake"
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// testBackend returns one item per page, so every helper has to follow the
+// pagination tokens to see everything.
+func testBackend() *fake.Backend {
+	return &fake.Backend{
+		MasterAccountId: "111",
+		Accounts: []*organizations.Account{
+			{Id: aws.String("111"), Name: aws.String("master")},
+			{Id: aws.String("222"), Name: aws.String("dev")},
+			{Id: aws.String("333"), Name: aws.String("prod")},
+		},
+		OrganizationalUnits: []*organizations.OrganizationalUnit{
+			{Id: aws.String("ou-a"), Name: aws.String("a")},
+			{Id: aws.String("ou-b"), Name: aws.String("b")},
+			{Id: aws.String("ou-c"), Name: aws.String("c")},
+		},
+		Parents: map[string]string{"ou-c": "ou-a", "222": "ou-a", "333": "ou-c"},
+		Tags: map[string]map[string]string{
+			"222": {"team": "dev", "env": "test", "owner": "alice"},
+		},
+		Budgets: []*budgets.Budget{
+			{BudgetName: aws.String("total")},
+			{BudgetName: aws.String("dev")},
+			{BudgetName: aws.String("prod")},
+		},
+		CostCategoryDefinitions: []*costexplorer.CostCategoryReference{
+			{Name: aws.String("BusinessUnit")},
+			{Name: aws.String("Environment")},
+		},
+		Costs: []fake.Cost{
+			// January has three services, February only one, so the groups
+			// of January span more pages than those of February.
+			{Date: day(1, 10), AccountId: "111", Service: "Amazon EC2", Region: "eu-west-1", Tags: map[string]string{"team": "platform"}, CostCategories: map[string]string{"BusinessUnit": "Engineering"}, Amount: 10},
+			{Date: day(1, 11), AccountId: "222", Service: "Amazon S3", Region: "us-east-1", Tags: map[string]string{"team": "dev", "env": "test"}, CostCategories: map[string]string{"BusinessUnit": "Research"}, Amount: 20},
+			{Date: day(1, 12), AccountId: "333", Service: "AWS Lambda", Region: "eu-central-1", CostCategories: map[string]string{"Environment": "prod"}, Amount: 30},
+			{Date: day(2, 10), AccountId: "111", Service: "Amazon EC2", Region: "eu-west-1", Amount: 40},
+		},
+		AnomalyMonitors: []*costexplorer.AnomalyMonitor{
+			{MonitorArn: aws.String("arn:monitor/a"), MonitorName: aws.String("a")},
+			{MonitorArn: aws.String("arn:monitor/b"), MonitorName: aws.String("b")},
+		},
+		Anomalies: []*costexplorer.Anomaly{
+			{AnomalyId: aws.String("a-1"), AnomalyStartDate: aws.String("2024-01-10")},
+			{AnomalyId: aws.String("a-2"), AnomalyStartDate: aws.String("2024-01-11")},
+			{AnomalyId: aws.String("a-3"), AnomalyStartDate: aws.String("2024-01-12")},
+		},
+		PageSize: 1,
+		Now: func() time.Time {
+			return day(3, 1)
+		},
+	}
+}
+
+var (
+	january = &costexplorer.DateInterval{Start: aws.String("2024-01-01"), End: aws.String("2024-02-01")}
+	quarter = &costexplorer.DateInterval{Start: aws.String("2024-01-01"), End: aws.String("2024-03-01")}
+)
+
+func TestListAccounts(t *testing.T) {
+	accounts, err := billing.ListAccounts(testBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, account := range accounts {
+		got = append(got, aws.StringValue(account.Id))
+	}
+
+	if want := []string{"111", "222", "333"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAccounts() = %v, want %v", got, want)
+	}
+}
+
+func TestListTagsForResource(t *testing.T) {
+	tags, err := billing.ListTagsForResource(testBackend(), "222")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"team": "dev", "env": "test", "owner": "alice"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("ListTagsForResource() = %v, want %v", tags, want)
+	}
+}
+
+func TestDescribeOrganizationTree(t *testing.T) {
+	root, err := billing.DescribeOrganizationTree(testBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	units := map[string][]string{}
+	root.Walk(func(ou *billing.OrganizationalUnit) {
+		units[ou.Name] = ou.AccountIds()
+	})
+
+	for _, ids := range units {
+		sort.Strings(ids)
+	}
+
+	want := map[string][]string{
+		"Root": {"111", "222", "333"},
+		"a":    {"222", "333"},
+		"b":    {},
+		"c":    {"333"},
+	}
+	if !reflect.DeepEqual(units, want) {
+		t.Errorf("DescribeOrganizationTree() = %v, want %v", units, want)
+	}
+}
+
+func TestDescribeBudgets(t *testing.T) {
+	budgetList, err := billing.DescribeBudgets(testBackend(), "111")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, budget := range budgetList {
+		got = append(got, aws.StringValue(budget.BudgetName))
+	}
+
+	if want := []string{"total", "dev", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DescribeBudgets() = %v, want %v", got, want)
+	}
+}
+
+func TestGetDimensionValues(t *testing.T) {
+	values, err := billing.GetDimensionValues(testBackend(), &costexplorer.GetDimensionValuesInput{
+		Dimension:  aws.String("SERVICE"),
+		TimePeriod: quarter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, value := range values {
+		got = append(got, aws.StringValue(value.Value))
+	}
+
+	if want := []string{"AWS Lambda", "Amazon EC2", "Amazon S3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDimensionValues() = %v, want %v", got, want)
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		tagKey *string
+		want   []string
+	}{
+		{name: "keys", want: []string{"env", "team"}},
+		{name: "values", tagKey: aws.String("team"), want: []string{"dev", "platform"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := billing.GetTags(testBackend(), &costexplorer.GetTagsInput{
+				TagKey:     tt.tagKey,
+				TimePeriod: quarter,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCostCategories(t *testing.T) {
+	tests := []struct {
+		name         string
+		categoryName *string
+		want         []string
+	}{
+		{name: "names", want: []string{"BusinessUnit", "Environment"}},
+		{name: "values", categoryName: aws.String("BusinessUnit"), want: []string{"Engineering", "Research"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := billing.GetCostCategories(testBackend(), &costexplorer.GetCostCategoriesInput{
+				CostCategoryName: tt.categoryName,
+				TimePeriod:       quarter,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCostCategories() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCostCategoryDefinitions(t *testing.T) {
+	references, err := billing.ListCostCategoryDefinitions(testBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, reference := range references {
+		got = append(got, aws.StringValue(reference.Name))
+	}
+
+	if want := []string{"BusinessUnit", "Environment"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListCostCategoryDefinitions() = %v, want %v", got, want)
+	}
+}
+
+func TestGetCostAndUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		timePeriod *costexplorer.DateInterval
+		groupBy    []*costexplorer.GroupDefinition
+		// want lists the groups of each period by their keys and amounts.
+		want map[string]map[string]string
+	}{
+		{
+			name:       "total",
+			timePeriod: quarter,
+			want: map[string]map[string]string{
+				"2024-01-01": {"": "60"},
+				"2024-02-01": {"": "40"},
+			},
+		},
+		{
+			name:       "by service",
+			timePeriod: january,
+			groupBy: []*costexplorer.GroupDefinition{
+				{Type: aws.String("DIMENSION"), Key: aws.String("SERVICE")},
+			},
+			want: map[string]map[string]string{
+				"2024-01-01": {"AWS Lambda": "30", "Amazon EC2": "10", "Amazon S3": "20"},
+			},
+		},
+		{
+			name:       "by account and region over months",
+			timePeriod: quarter,
+			groupBy: []*costexplorer.GroupDefinition{
+				{Type: aws.String("DIMENSION"), Key: aws.String("LINKED_ACCOUNT")},
+				{Type: aws.String("DIMENSION"), Key: aws.String("REGION")},
+			},
+			want: map[string]map[string]string{
+				"2024-01-01": {"111/eu-west-1": "10", "222/us-east-1": "20", "333/eu-central-1": "30"},
+				"2024-02-01": {"111/eu-west-1": "40"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := billing.GetCostAndUsage(testBackend(), &costexplorer.GetCostAndUsageInput{
+				Granularity: aws.String("MONTHLY"),
+				GroupBy:     tt.groupBy,
+				Metrics:     []*string{aws.String("UnblendedCost")},
+				TimePeriod:  tt.timePeriod,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.NextPageToken != nil {
+				t.Errorf("NextPageToken = %q, want nil", aws.StringValue(result.NextPageToken))
+			}
+
+			got := map[string]map[string]string{}
+			for _, resultByTime := range result.ResultsByTime {
+				groups := map[string]string{}
+
+				if len(tt.groupBy) == 0 {
+					groups[""] = aws.StringValue(resultByTime.Total["UnblendedCost"].Amount)
+				}
+				for _, group := range resultByTime.Groups {
+					key := ""
+					for i, k := range aws.StringValueSlice(group.Keys) {
+						if i > 0 {
+							key += "/"
+						}
+						key += k
+					}
+
+					if _, ok := groups[key]; ok {
+						t.Errorf("group %s appears twice in %s", key, aws.StringValue(resultByTime.TimePeriod.Start))
+					}
+					groups[key] = aws.StringValue(group.Metrics["UnblendedCost"].Amount)
+				}
+
+				got[aws.StringValue(resultByTime.TimePeriod.Start)] = groups
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCostAndUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAnomalies(t *testing.T) {
+	anomalies, err := billing.GetAnomalies(testBackend(), &costexplorer.GetAnomaliesInput{
+		DateInterval: &costexplorer.AnomalyDateInterval{
+			StartDate: aws.String("2024-01-01"),
+			EndDate:   aws.String("2024-01-31"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, anomaly := range anomalies {
+		got = append(got, aws.StringValue(anomaly.AnomalyId))
+	}
+
+	if want := []string{"a-1", "a-2", "a-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAnomalies() = %v, want %v", got, want)
+	}
+}
+
+func TestGetAnomalyMonitors(t *testing.T) {
+	monitors, err := billing.GetAnomalyMonitors(testBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, monitor := range monitors {
+		got = append(got, aws.StringValue(monitor.MonitorName))
+	}
+
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAnomalyMonitors() = %v, want %v", got, want)
+	}
+}
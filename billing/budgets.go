@@ -0,0 +1,31 @@
+package billing
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/budgets"
+)
+
+// DescribeBudgets follows NextToken and returns the budgets of all pages.
+func DescribeBudgets(src BudgetSource, accountId string) ([]*budgets.Budget, error) {
+	input := &budgets.DescribeBudgetsInput{
+		AccountId: aws.String(accountId),
+	}
+
+	result := []*budgets.Budget{}
+
+	for {
+		page, err := src.DescribeBudgets(input)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, page.Budgets...)
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package billing
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// GetCostAndUsage follows NextPageToken and merges the groups of all pages
+// into a single output.
+func GetCostAndUsage(src CostSource, input *costexplorer.GetCostAndUsageInput) (*costexplorer.GetCostAndUsageOutput, error) {
+	in := *input
+	in.NextPageToken = nil
+
+	var result *costexplorer.GetCostAndUsageOutput
+
+	for {
+		page, err := src.GetCostAndUsage(&in)
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			result = page
+		} else {
+			mergeResultsByTime(result, page.ResultsByTime)
+			result.DimensionValueAttributes = append(result.DimensionValueAttributes, page.DimensionValueAttributes...)
+		}
+
+		if aws.StringValue(page.NextPageToken) == "" {
+			break
+		}
+		in.NextPageToken = page.NextPageToken
+	}
+
+	result.NextPageToken = nil
+
+	return result, nil
+}
+
+func mergeResultsByTime(result *costexplorer.GetCostAndUsageOutput, resultsByTime []*costexplorer.ResultByTime) {
+	for _, resultByTime := range resultsByTime {
+		merged := false
+
+		for _, r := range result.ResultsByTime {
+			if aws.StringValue(r.TimePeriod.Start) == aws.StringValue(resultByTime.TimePeriod.Start) {
+				r.Groups = append(r.Groups, resultByTime.Groups...)
+				merged = true
+				break
+			}
+		}
+
+		if !merged {
+			result.ResultsByTime = append(result.ResultsByTime, resultByTime)
+		}
+	}
+}
+
+// GetDimensionValues follows NextPageToken and returns the dimension values
+// of all pages.
+func GetDimensionValues(src CostSource, input *costexplorer.GetDimensionValuesInput) ([]*costexplorer.DimensionValuesWithAttributes, error) {
+	in := *input
+	in.NextPageToken = nil
+
+	dimensionValues := []*costexplorer.DimensionValuesWithAttributes{}
+
+	for {
+		page, err := src.GetDimensionValues(&in)
+		if err != nil {
+			return nil, err
+		}
+
+		dimensionValues = append(dimensionValues, page.DimensionValues...)
+
+		if aws.StringValue(page.NextPageToken) == "" {
+			break
+		}
+		in.NextPageToken = page.NextPageToken
+	}
+
+	return dimensionValues, nil
+}
+
+// And combines the given expressions, skipping nil ones. It returns nil if
+// there is nothing to combine, as Cost Explorer rejects empty expressions.
+func And(expressions ...*costexplorer.Expression) *costexplorer.Expression {
+	nonNil := []*costexplorer.Expression{}
+	for _, e := range expressions {
+		if e != nil {
+			nonNil = append(nonNil, e)
+		}
+	}
+
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	default:
+		return &costexplorer.Expression{And: nonNil}
+	}
+}
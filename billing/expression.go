@@ -0,0 +1,361 @@
+package billing
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// ParseExpression parses a filter like
+//
+//	service is "Amazon Simple Storage Service" and region in eu-west-1,eu-central-1 and not tag:team=platform
+//
+// into a Cost Explorer expression. Conditions compare a dimension, a tag
+// (tag:<key>) or a cost category (costcategory:<name>) with one or more comma
+// separated values using is, =, in, != or "not in". Conditions are combined
+// with and, or, not and parentheses, and binds tighter than or. Values
+// containing spaces or special characters must be quoted.
+func ParseExpression(s string) (*costexplorer.Expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	expression, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, p.errorf(t, "unexpected %s, expected and, or or end of filter", t)
+	}
+
+	return expression, nil
+}
+
+// DimensionKey returns the Cost Explorer dimension for a name like
+// "service", "usage-type" or "account".
+func DimensionKey(name string) (string, error) {
+	key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if key == "ACCOUNT" {
+		key = costexplorer.DimensionLinkedAccount
+	}
+
+	for _, dimension := range costexplorer.Dimension_Values() {
+		if dimension == key {
+			return key, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown dimension %q", name)
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenComma
+	tokenLeft
+	tokenRight
+	tokenEquals
+	tokenNotEquals
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEnd {
+		return "end of filter"
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is returns whether the token is the given keyword, ignoring case.
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`,()=!"'`, r)
+}
+
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", start: i})
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeft, text: "(", start: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRight, text: ")", start: i})
+			i++
+
+		case r == '=':
+			tokens = append(tokens, token{kind: tokenEquals, text: "=", start: i})
+			i++
+
+		case r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, fmt.Errorf("invalid filter at position %d: expected != but found !", i+1)
+			}
+			tokens = append(tokens, token{kind: tokenNotEquals, text: "!=", start: i})
+			i += 2
+
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("invalid filter at position %d: unterminated string", start+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), start: start})
+			i++
+
+		default:
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), start: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, start: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("invalid filter at position %d: %s", t.start+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) or() (*costexplorer.Expression, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := []*costexplorer.Expression{first}
+	for p.peek().is("or") {
+		p.next()
+
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, e)
+	}
+
+	if len(expressions) == 1 {
+		return first, nil
+	}
+
+	return &costexplorer.Expression{Or: expressions}, nil
+}
+
+func (p *parser) and() (*costexplorer.Expression, error) {
+	first, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := []*costexplorer.Expression{first}
+	for p.peek().is("and") {
+		p.next()
+
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, e)
+	}
+
+	return And(expressions...), nil
+}
+
+func (p *parser) unary() (*costexplorer.Expression, error) {
+	t := p.peek()
+
+	switch {
+	case t.is("not"):
+		p.next()
+
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &costexplorer.Expression{Not: e}, nil
+
+	case t.kind == tokenLeft:
+		p.next()
+
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if r := p.next(); r.kind != tokenRight {
+			return nil, p.errorf(r, "unexpected %s, expected )", r)
+		}
+
+		return e, nil
+
+	default:
+		return p.condition()
+	}
+}
+
+// condition parses <key> <operator> <values>.
+func (p *parser) condition() (*costexplorer.Expression, error) {
+	keyToken := p.next()
+	if keyToken.kind != tokenWord {
+		return nil, p.errorf(keyToken, "unexpected %s, expected a dimension, tag:<key> or costcategory:<name>", keyToken)
+	}
+
+	negated, err := p.operator()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := p.values()
+	if err != nil {
+		return nil, err
+	}
+
+	var e *costexplorer.Expression
+
+	prefix, name, found := strings.Cut(keyToken.text, ":")
+	switch {
+	case found && strings.EqualFold(prefix, "tag"):
+		e = &costexplorer.Expression{
+			Tags: &costexplorer.TagValues{
+				Key:    aws.String(name),
+				Values: aws.StringSlice(values),
+			},
+		}
+
+	case found && (strings.EqualFold(prefix, "costcategory") || strings.EqualFold(prefix, "cc")):
+		e = &costexplorer.Expression{
+			CostCategories: &costexplorer.CostCategoryValues{
+				Key:    aws.String(name),
+				Values: aws.StringSlice(values),
+			},
+		}
+
+	case found:
+		return nil, p.errorf(keyToken, "unknown prefix %q, expected tag: or costcategory:", prefix)
+
+	default:
+		key, err := DimensionKey(keyToken.text)
+		if err != nil {
+			return nil, p.errorf(keyToken, "%s", err)
+		}
+
+		e = &costexplorer.Expression{
+			Dimensions: &costexplorer.DimensionValues{
+				Key:    aws.String(key),
+				Values: aws.StringSlice(values),
+			},
+		}
+	}
+
+	if negated {
+		return &costexplorer.Expression{Not: e}, nil
+	}
+
+	return e, nil
+}
+
+// operator parses is, =, in, !=, "is not" or "not in" and returns whether
+// the condition is negated.
+func (p *parser) operator() (bool, error) {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenEquals || t.is("in"):
+		return false, nil
+
+	case t.kind == tokenNotEquals:
+		return true, nil
+
+	case t.is("is"):
+		if p.peek().is("not") {
+			p.next()
+			return true, nil
+		}
+		return false, nil
+
+	case t.is("not"):
+		if in := p.next(); !in.is("in") {
+			return false, p.errorf(in, "unexpected %s, expected in", in)
+		}
+		return true, nil
+
+	default:
+		return false, p.errorf(t, "unexpected %s, expected is, =, in, != or not in", t)
+	}
+}
+
+func (p *parser) values() ([]string, error) {
+	values := []string{}
+
+	for {
+		t := p.next()
+		if t.kind != tokenWord && t.kind != tokenString {
+			return nil, p.errorf(t, "unexpected %s, expected a value", t)
+		}
+		values = append(values, t.text)
+
+		if p.peek().kind != tokenComma {
+			return values, nil
+		}
+		p.next()
+	}
+}
@@ -0,0 +1,170 @@
+package billing_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+
+	"github.com/giantswarm/abu/billing"
+)
+
+func dimension(key string, values ...string) *costexplorer.Expression {
+	return &costexplorer.Expression{
+		Dimensions: &costexplorer.DimensionValues{Key: aws.String(key), Values: aws.StringSlice(values)},
+	}
+}
+
+func tag(key string, values ...string) *costexplorer.Expression {
+	return &costexplorer.Expression{
+		Tags: &costexplorer.TagValues{Key: aws.String(key), Values: aws.StringSlice(values)},
+	}
+}
+
+func costCategory(key string, values ...string) *costexplorer.Expression {
+	return &costexplorer.Expression{
+		CostCategories: &costexplorer.CostCategoryValues{Key: aws.String(key), Values: aws.StringSlice(values)},
+	}
+}
+
+func and(expressions ...*costexplorer.Expression) *costexplorer.Expression {
+	return &costexplorer.Expression{And: expressions}
+}
+
+func or(expressions ...*costexplorer.Expression) *costexplorer.Expression {
+	return &costexplorer.Expression{Or: expressions}
+}
+
+func not(expression *costexplorer.Expression) *costexplorer.Expression {
+	return &costexplorer.Expression{Not: expression}
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want *costexplorer.Expression
+	}{
+		{
+			name: "example",
+			s:    `service is "Amazon Simple Storage Service" and region in eu-west-1,eu-central-1 and not tag:team=platform`,
+			want: and(
+				dimension("SERVICE", "Amazon Simple Storage Service"),
+				dimension("REGION", "eu-west-1", "eu-central-1"),
+				not(tag("team", "platform")),
+			),
+		},
+		{
+			name: "and binds tighter than or",
+			s:    "region = a or region = b and service = c",
+			want: or(
+				dimension("REGION", "a"),
+				and(dimension("REGION", "b"), dimension("SERVICE", "c")),
+			),
+		},
+		{
+			name: "parentheses",
+			s:    "(region = a or region = b) and service = c",
+			want: and(
+				or(dimension("REGION", "a"), dimension("REGION", "b")),
+				dimension("SERVICE", "c"),
+			),
+		},
+		{
+			name: "not before parentheses",
+			s:    "not (region = a or account = 111)",
+			want: not(or(dimension("REGION", "a"), dimension("LINKED_ACCOUNT", "111"))),
+		},
+		{
+			name: "keywords ignore case",
+			s:    "Region IN a AND NOT service IS b",
+			want: and(dimension("REGION", "a"), not(dimension("SERVICE", "b"))),
+		},
+		{
+			name: "is not",
+			s:    "service is not a",
+			want: not(dimension("SERVICE", "a")),
+		},
+		{
+			name: "not in",
+			s:    "region not in a,b",
+			want: not(dimension("REGION", "a", "b")),
+		},
+		{
+			name: "not equals",
+			s:    "usage-type != a",
+			want: not(dimension("USAGE_TYPE", "a")),
+		},
+		{
+			name: "tag",
+			s:    "tag:team in platform,dev",
+			want: tag("team", "platform", "dev"),
+		},
+		{
+			name: "cost category",
+			s:    "costcategory:BusinessUnit = Engineering",
+			want: costCategory("BusinessUnit", "Engineering"),
+		},
+		{
+			name: "cost category short",
+			s:    "cc:BusinessUnit = Engineering",
+			want: costCategory("BusinessUnit", "Engineering"),
+		},
+		{
+			name: "quoted values",
+			s:    `service in "Amazon EC2, Elastic",'AWS Lambda'`,
+			want: dimension("SERVICE", "Amazon EC2, Elastic", "AWS Lambda"),
+		},
+		{
+			name: "escapes",
+			s:    `tag:note = "say \"hi\" \\ bye" or tag:note = 'it\'s'`,
+			want: or(tag("note", `say "hi" \ bye`), tag("note", "it's")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := billing.ParseExpression(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExpression(%q) = %s, want %s", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "(service = x", want: "at position 13: unexpected end of filter, expected )"},
+		{s: "service in", want: "at position 11: unexpected end of filter, expected a value"},
+		{s: `service = "Amazon S3`, want: "at position 11: unterminated string"},
+		{s: "team:tag = x", want: `at position 1: unknown prefix "team"`},
+		{s: "servce = x", want: `at position 1: unknown dimension "servce"`},
+		{s: "service like x", want: `at position 9: unexpected "like", expected is, =, in, != or not in`},
+		{s: "service not x", want: `at position 13: unexpected "x", expected in`},
+		{s: "service ! x", want: "at position 9: expected != but found !"},
+		{s: "service = x region = y", want: `at position 13: unexpected "region", expected and, or or end of filter`},
+		{s: "", want: "at position 1: unexpected end of filter, expected a dimension"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			_, err := billing.ParseExpression(tt.s)
+			if err == nil {
+				t.Fatalf("ParseExpression(%q) succeeded, want an error", tt.s)
+			}
+
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseExpression(%q) = %q, want it to contain %q", tt.s, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,867 @@
+package fake
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/budgets"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/organizations"
+
+	"github.com/giantswarm/abu/billing"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	rootId     = "r-root"
+)
+
+// Cost is a single day of spend of one service in one region of one account.
+type Cost struct {
+	Date           time.Time
+	AccountId      string
+	Service        string
+	Region         string
+	UsageType      string
+	InstanceType   string
+	PurchaseType   string
+	Tags           map[string]string
+	CostCategories map[string]string
+	Amount         float64
+}
+
+// Backend is an in-memory implementation of all billing sources.
+type Backend struct {
+	MasterAccountId string
+	Accounts        []*organizations.Account
+	Costs           []Cost
+	Forecasts       map[string]float64
+	Budgets         []*budgets.Budget
+
+	// OrganizationalUnits are placed below their parent in Parents, which
+	// maps ids of units and accounts to the id of their parent. Anything
+	// without a parent is placed below the root.
+	OrganizationalUnits []*organizations.OrganizationalUnit
+	Parents             map[string]string
+
+	// Tags are the tags of accounts, units and roots by their id.
+	Tags map[string]map[string]string
+
+	CostCategoryDefinitions []*costexplorer.CostCategoryReference
+
+	Anomalies       []*costexplorer.Anomaly
+	AnomalyMonitors []*costexplorer.AnomalyMonitor
+
+	// PageSize limits the number of items returned per page, zero disables
+	// pagination.
+	PageSize int
+
+	// Now is used to decide which periods are estimated and is the clock of
+	// the backend, defaults to time.Now.
+	Now func() time.Time
+}
+
+func (b *Backend) Backend() *billing.Backend {
+	return &billing.Backend{
+		Accounts:  b,
+		Costs:     b,
+		Forecasts: b,
+		Anomalies: b,
+		Budgets:   b,
+		Clock:     b.now,
+	}
+}
+
+func (b *Backend) DescribeOrganization(input *organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error) {
+	return &organizations.DescribeOrganizationOutput{
+		Organization: &organizations.Organization{
+			MasterAccountId: aws.String(b.MasterAccountId),
+		},
+	}, nil
+}
+
+func (b *Backend) ListAccounts(input *organizations.ListAccountsInput) (*organizations.ListAccountsOutput, error) {
+	start, end, next, err := b.page(len(b.Accounts), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &organizations.ListAccountsOutput{
+		Accounts:  b.Accounts[start:end],
+		NextToken: next,
+	}, nil
+}
+
+func (b *Backend) ListRoots(input *organizations.ListRootsInput) (*organizations.ListRootsOutput, error) {
+	return &organizations.ListRootsOutput{
+		Roots: []*organizations.Root{
+			{Id: aws.String(rootId), Name: aws.String("Root")},
+		},
+	}, nil
+}
+
+func (b *Backend) ListOrganizationalUnitsForParent(input *organizations.ListOrganizationalUnitsForParentInput) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	units := []*organizations.OrganizationalUnit{}
+	for _, unit := range b.OrganizationalUnits {
+		if b.parent(aws.StringValue(unit.Id)) == aws.StringValue(input.ParentId) {
+			units = append(units, unit)
+		}
+	}
+
+	start, end, next, err := b.page(len(units), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &organizations.ListOrganizationalUnitsForParentOutput{
+		OrganizationalUnits: units[start:end],
+		NextToken:           next,
+	}, nil
+}
+
+func (b *Backend) ListAccountsForParent(input *organizations.ListAccountsForParentInput) (*organizations.ListAccountsForParentOutput, error) {
+	accounts := []*organizations.Account{}
+	for _, account := range b.Accounts {
+		if b.parent(aws.StringValue(account.Id)) == aws.StringValue(input.ParentId) {
+			accounts = append(accounts, account)
+		}
+	}
+
+	start, end, next, err := b.page(len(accounts), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &organizations.ListAccountsForParentOutput{
+		Accounts:  accounts[start:end],
+		NextToken: next,
+	}, nil
+}
+
+func (b *Backend) ListTagsForResource(input *organizations.ListTagsForResourceInput) (*organizations.ListTagsForResourceOutput, error) {
+	keys := []string{}
+	for key := range b.Tags[aws.StringValue(input.ResourceId)] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start, end, next, err := b.page(len(keys), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &organizations.ListTagsForResourceOutput{
+		NextToken: next,
+	}
+	for _, key := range keys[start:end] {
+		output.Tags = append(output.Tags, &organizations.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(b.Tags[aws.StringValue(input.ResourceId)][key]),
+		})
+	}
+
+	return output, nil
+}
+
+func (b *Backend) parent(id string) string {
+	if parent, ok := b.Parents[id]; ok {
+		return parent
+	}
+
+	return rootId
+}
+
+func (b *Backend) GetCostAndUsage(input *costexplorer.GetCostAndUsageInput) (*costexplorer.GetCostAndUsageOutput, error) {
+	periods, err := b.periods(input.TimePeriod, aws.StringValue(input.Granularity))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &costexplorer.GetCostAndUsageOutput{}
+
+	// Groups are paged across all periods, so a page may end in the middle
+	// of one period and leave out others that have fewer groups.
+	periodKeys := [][][]string{}
+	periodTotals := []map[string]float64{}
+	pageSize := 0
+
+	for _, period := range periods {
+		totals := map[string]float64{}
+		keys := [][]string{}
+
+		for _, cost := range b.Costs {
+			if cost.Date.Before(period.start) || !cost.Date.Before(period.end) {
+				continue
+			}
+
+			match, err := cost.matches(input.Filter)
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				continue
+			}
+
+			groupKeys := []string{}
+			for _, groupBy := range input.GroupBy {
+				value, err := cost.groupKey(groupBy)
+				if err != nil {
+					return nil, err
+				}
+				groupKeys = append(groupKeys, value)
+			}
+
+			key := fmt.Sprint(groupKeys)
+			if _, ok := totals[key]; !ok {
+				keys = append(keys, groupKeys)
+			}
+			totals[key] += cost.Amount
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+
+		periodKeys = append(periodKeys, keys)
+		periodTotals = append(periodTotals, totals)
+		pageSize = max(pageSize, len(keys))
+	}
+
+	first, last, next, err := b.page(pageSize, input.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.GroupBy) > 0 {
+		output.NextPageToken = next
+	}
+
+	for i, period := range periods {
+		keys, totals := periodKeys[i], periodTotals[i]
+
+		resultByTime := &costexplorer.ResultByTime{
+			Estimated: aws.Bool(period.end.After(b.now())),
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String(period.start.Format(dateLayout)),
+				End:   aws.String(period.end.Format(dateLayout)),
+			},
+		}
+
+		if len(input.GroupBy) == 0 {
+			resultByTime.Total = metrics(input.Metrics, totals[fmt.Sprint([]string{})])
+		} else {
+			for _, groupKeys := range keys[min(first, len(keys)):min(last, len(keys))] {
+				resultByTime.Groups = append(resultByTime.Groups, &costexplorer.Group{
+					Keys:    aws.StringSlice(groupKeys),
+					Metrics: metrics(input.Metrics, totals[fmt.Sprint(groupKeys)]),
+				})
+			}
+		}
+
+		output.ResultsByTime = append(output.ResultsByTime, resultByTime)
+	}
+
+	return output, nil
+}
+
+func (b *Backend) GetDimensionValues(input *costexplorer.GetDimensionValuesInput) (*costexplorer.GetDimensionValuesOutput, error) {
+	start, end, err := parseInterval(input.TimePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	values := []string{}
+
+	for _, cost := range b.Costs {
+		if cost.Date.Before(start) || !cost.Date.Before(end) {
+			continue
+		}
+
+		match, err := cost.matches(input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		value, err := cost.dimension(aws.StringValue(input.Dimension))
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	sort.Strings(values)
+
+	first, last, next, err := b.page(len(values), input.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &costexplorer.GetDimensionValuesOutput{
+		NextPageToken: next,
+	}
+	for _, value := range values[first:last] {
+		output.DimensionValues = append(output.DimensionValues, &costexplorer.DimensionValuesWithAttributes{
+			Value: aws.String(value),
+		})
+	}
+
+	return output, nil
+}
+
+func (b *Backend) GetTags(input *costexplorer.GetTagsInput) (*costexplorer.GetTagsOutput, error) {
+	start, end, err := parseInterval(input.TimePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	tags := []string{}
+
+	for _, cost := range b.Costs {
+		if cost.Date.Before(start) || !cost.Date.Before(end) {
+			continue
+		}
+
+		match, err := cost.matches(input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		for key, value := range cost.Tags {
+			tag := key
+			if input.TagKey != nil {
+				if key != aws.StringValue(input.TagKey) {
+					continue
+				}
+				tag = value
+			}
+
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	sort.Strings(tags)
+
+	first, last, next, err := b.page(len(tags), input.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &costexplorer.GetTagsOutput{
+		Tags:          aws.StringSlice(tags[first:last]),
+		NextPageToken: next,
+	}, nil
+}
+
+func (b *Backend) GetCostCategories(input *costexplorer.GetCostCategoriesInput) (*costexplorer.GetCostCategoriesOutput, error) {
+	start, end, err := parseInterval(input.TimePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	categories := []string{}
+
+	for _, cost := range b.Costs {
+		if cost.Date.Before(start) || !cost.Date.Before(end) {
+			continue
+		}
+
+		match, err := cost.matches(input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		for name, value := range cost.CostCategories {
+			category := name
+			if input.CostCategoryName != nil {
+				if name != aws.StringValue(input.CostCategoryName) {
+					continue
+				}
+				category = value
+			}
+
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+
+	sort.Strings(categories)
+
+	first, last, next, err := b.page(len(categories), input.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &costexplorer.GetCostCategoriesOutput{
+		NextPageToken: next,
+		ReturnSize:    aws.Int64(int64(last - first)),
+		TotalSize:     aws.Int64(int64(len(categories))),
+	}
+	if input.CostCategoryName != nil {
+		output.CostCategoryValues = aws.StringSlice(categories[first:last])
+	} else {
+		output.CostCategoryNames = aws.StringSlice(categories[first:last])
+	}
+
+	return output, nil
+}
+
+func (b *Backend) ListCostCategoryDefinitions(input *costexplorer.ListCostCategoryDefinitionsInput) (*costexplorer.ListCostCategoryDefinitionsOutput, error) {
+	start, end, next, err := b.page(len(b.CostCategoryDefinitions), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &costexplorer.ListCostCategoryDefinitionsOutput{
+		CostCategoryReferences: b.CostCategoryDefinitions[start:end],
+		NextToken:              next,
+	}, nil
+}
+
+func (b *Backend) GetCostForecast(input *costexplorer.GetCostForecastInput) (*costexplorer.GetCostForecastOutput, error) {
+	found := false
+	total := 0.0
+
+	for accountId, forecast := range b.Forecasts {
+		if matchesAccount(input.Filter, accountId) {
+			found = true
+			total += forecast
+		}
+	}
+
+	if !found {
+		return nil, awserr.New(costexplorer.ErrCodeDataUnavailableException, "insufficient amount of historical data to generate forecast", nil)
+	}
+
+	// The prediction interval widens with the confidence level.
+	level := float64(aws.Int64Value(input.PredictionIntervalLevel))
+	if level == 0 {
+		level = 80
+	}
+	spread := total * level / 500
+
+	return &costexplorer.GetCostForecastOutput{
+		ForecastResultsByTime: []*costexplorer.ForecastResult{
+			{
+				MeanValue:                    aws.String(strconv.FormatFloat(total, 'f', -1, 64)),
+				PredictionIntervalLowerBound: aws.String(strconv.FormatFloat(total-spread, 'f', -1, 64)),
+				PredictionIntervalUpperBound: aws.String(strconv.FormatFloat(total+spread, 'f', -1, 64)),
+				TimePeriod:                   input.TimePeriod,
+			},
+		},
+		Total: &costexplorer.MetricValue{
+			Amount: aws.String(strconv.FormatFloat(total, 'f', -1, 64)),
+			Unit:   aws.String("USD"),
+		},
+	}, nil
+}
+
+func (b *Backend) GetAnomalies(input *costexplorer.GetAnomaliesInput) (*costexplorer.GetAnomaliesOutput, error) {
+	if input.DateInterval == nil {
+		return nil, fmt.Errorf("missing date interval")
+	}
+
+	from := aws.StringValue(input.DateInterval.StartDate)
+	to := aws.StringValue(input.DateInterval.EndDate)
+
+	anomalies := []*costexplorer.Anomaly{}
+	for _, anomaly := range b.Anomalies {
+		// Anomalies are selected by the day they ended, or are ongoing.
+		date := aws.StringValue(anomaly.AnomalyEndDate)
+		if date == "" {
+			date = aws.StringValue(anomaly.AnomalyStartDate)
+		}
+		if date < from || (to != "" && date > to) {
+			continue
+		}
+
+		if input.MonitorArn != nil && aws.StringValue(anomaly.MonitorArn) != aws.StringValue(input.MonitorArn) {
+			continue
+		}
+		if input.Feedback != nil && aws.StringValue(anomaly.Feedback) != aws.StringValue(input.Feedback) {
+			continue
+		}
+		if input.TotalImpact != nil && anomaly.Impact != nil && !matchesImpact(input.TotalImpact, aws.Float64Value(anomaly.Impact.TotalImpact)) {
+			continue
+		}
+
+		anomalies = append(anomalies, anomaly)
+	}
+
+	start, end, next, err := b.page(len(anomalies), input.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &costexplorer.GetAnomaliesOutput{
+		Anomalies:     anomalies[start:end],
+		NextPageToken: next,
+	}, nil
+}
+
+func matchesImpact(filter *costexplorer.TotalImpactFilter, impact float64) bool {
+	value, endValue := aws.Float64Value(filter.StartValue), aws.Float64Value(filter.EndValue)
+
+	switch aws.StringValue(filter.NumericOperator) {
+	case costexplorer.NumericOperatorEqual:
+		return impact == value
+	case costexplorer.NumericOperatorGreaterThan:
+		return impact > value
+	case costexplorer.NumericOperatorGreaterThanOrEqual:
+		return impact >= value
+	case costexplorer.NumericOperatorLessThan:
+		return impact < value
+	case costexplorer.NumericOperatorLessThanOrEqual:
+		return impact <= value
+	case costexplorer.NumericOperatorBetween:
+		return impact >= value && impact <= endValue
+	}
+
+	return false
+}
+
+func (b *Backend) GetAnomalyMonitors(input *costexplorer.GetAnomalyMonitorsInput) (*costexplorer.GetAnomalyMonitorsOutput, error) {
+	monitors := []*costexplorer.AnomalyMonitor{}
+	for _, monitor := range b.AnomalyMonitors {
+		if len(input.MonitorArnList) == 0 || slices.Contains(aws.StringValueSlice(input.MonitorArnList), aws.StringValue(monitor.MonitorArn)) {
+			monitors = append(monitors, monitor)
+		}
+	}
+
+	start, end, next, err := b.page(len(monitors), input.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &costexplorer.GetAnomalyMonitorsOutput{
+		AnomalyMonitors: monitors[start:end],
+		NextPageToken:   next,
+	}, nil
+}
+
+func (b *Backend) ProvideAnomalyFeedback(input *costexplorer.ProvideAnomalyFeedbackInput) (*costexplorer.ProvideAnomalyFeedbackOutput, error) {
+	if !slices.Contains(costexplorer.AnomalyFeedbackType_Values(), aws.StringValue(input.Feedback)) {
+		return nil, fmt.Errorf("invalid feedback %q", aws.StringValue(input.Feedback))
+	}
+
+	for _, anomaly := range b.Anomalies {
+		if aws.StringValue(anomaly.AnomalyId) == aws.StringValue(input.AnomalyId) {
+			anomaly.Feedback = input.Feedback
+
+			return &costexplorer.ProvideAnomalyFeedbackOutput{AnomalyId: anomaly.AnomalyId}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown anomaly %q", aws.StringValue(input.AnomalyId))
+}
+
+func (b *Backend) DescribeBudgets(input *budgets.DescribeBudgetsInput) (*budgets.DescribeBudgetsOutput, error) {
+	start, end, next, err := b.page(len(b.Budgets), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &budgets.DescribeBudgetsOutput{
+		Budgets:   b.Budgets[start:end],
+		NextToken: next,
+	}, nil
+}
+
+func (b *Backend) budgetIndex(name *string) int {
+	return slices.IndexFunc(b.Budgets, func(budget *budgets.Budget) bool {
+		return aws.StringValue(budget.BudgetName) == aws.StringValue(name)
+	})
+}
+
+func (b *Backend) CreateBudget(input *budgets.CreateBudgetInput) (*budgets.CreateBudgetOutput, error) {
+	if input.Budget == nil || aws.StringValue(input.Budget.BudgetName) == "" {
+		return nil, fmt.Errorf("missing budget name")
+	}
+	if b.budgetIndex(input.Budget.BudgetName) >= 0 {
+		return nil, fmt.Errorf("budget %q already exists", aws.StringValue(input.Budget.BudgetName))
+	}
+
+	b.Budgets = append(b.Budgets, input.Budget)
+
+	return &budgets.CreateBudgetOutput{}, nil
+}
+
+func (b *Backend) UpdateBudget(input *budgets.UpdateBudgetInput) (*budgets.UpdateBudgetOutput, error) {
+	if input.NewBudget == nil {
+		return nil, fmt.Errorf("missing budget")
+	}
+	if input.NewBudget.BudgetLimit != nil && len(input.NewBudget.PlannedBudgetLimits) > 0 {
+		return nil, fmt.Errorf("budget %q has both a limit and planned limits", aws.StringValue(input.NewBudget.BudgetName))
+	}
+
+	i := b.budgetIndex(input.NewBudget.BudgetName)
+	if i < 0 {
+		return nil, fmt.Errorf("unknown budget %q", aws.StringValue(input.NewBudget.BudgetName))
+	}
+
+	b.Budgets[i] = input.NewBudget
+
+	return &budgets.UpdateBudgetOutput{}, nil
+}
+
+func (b *Backend) DeleteBudget(input *budgets.DeleteBudgetInput) (*budgets.DeleteBudgetOutput, error) {
+	i := b.budgetIndex(input.BudgetName)
+	if i < 0 {
+		return nil, fmt.Errorf("unknown budget %q", aws.StringValue(input.BudgetName))
+	}
+
+	b.Budgets = slices.Delete(b.Budgets, i, i+1)
+
+	return &budgets.DeleteBudgetOutput{}, nil
+}
+
+func (b *Backend) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+
+	return time.Now()
+}
+
+// page returns the bounds of the page identified by token, and the token of
+// the next page if there is one.
+func (b *Backend) page(n int, token *string) (int, int, *string, error) {
+	start := 0
+	if aws.StringValue(token) != "" {
+		var err error
+		start, err = strconv.Atoi(aws.StringValue(token))
+		if err != nil || start < 0 || start > n {
+			return 0, 0, nil, fmt.Errorf("invalid pagination token %q", aws.StringValue(token))
+		}
+	}
+
+	if b.PageSize <= 0 || start+b.PageSize >= n {
+		return start, n, nil, nil
+	}
+
+	end := start + b.PageSize
+
+	return start, end, aws.String(strconv.Itoa(end)), nil
+}
+
+type period struct {
+	start time.Time
+	end   time.Time
+}
+
+func (b *Backend) periods(interval *costexplorer.DateInterval, granularity string) ([]period, error) {
+	start, end, err := parseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := []period{}
+
+	for s := start; s.Before(end); {
+		var e time.Time
+
+		switch granularity {
+		case costexplorer.GranularityDaily:
+			e = s.AddDate(0, 0, 1)
+		case costexplorer.GranularityMonthly:
+			e = time.Date(s.Year(), s.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		default:
+			return nil, fmt.Errorf("unsupported granularity %q", granularity)
+		}
+
+		if e.After(end) {
+			e = end
+		}
+
+		periods = append(periods, period{start: s, end: e})
+		s = e
+	}
+
+	return periods, nil
+}
+
+func parseInterval(interval *costexplorer.DateInterval) (time.Time, time.Time, error) {
+	if interval == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("time period is required")
+	}
+
+	start, err := time.Parse(dateLayout, aws.StringValue(interval.Start))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end, err := time.Parse(dateLayout, aws.StringValue(interval.End))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return start, end, nil
+}
+
+func metrics(names []*string, amount float64) map[string]*costexplorer.MetricValue {
+	m := map[string]*costexplorer.MetricValue{}
+	for _, name := range names {
+		m[aws.StringValue(name)] = &costexplorer.MetricValue{
+			Amount: aws.String(strconv.FormatFloat(amount, 'f', -1, 64)),
+			Unit:   aws.String("USD"),
+		}
+	}
+
+	return m
+}
+
+func (c Cost) dimension(key string) (string, error) {
+	switch key {
+	case costexplorer.DimensionLinkedAccount:
+		return c.AccountId, nil
+	case costexplorer.DimensionService:
+		return c.Service, nil
+	case costexplorer.DimensionRegion:
+		return c.Region, nil
+	case costexplorer.DimensionUsageType:
+		return c.UsageType, nil
+	case costexplorer.DimensionInstanceType:
+		// Cost Explorer groups costs of services without instances like this.
+		if c.InstanceType == "" {
+			return "NoInstanceType", nil
+		}
+		return c.InstanceType, nil
+	case costexplorer.DimensionPurchaseType:
+		return c.PurchaseType, nil
+	default:
+		return "", fmt.Errorf("unsupported dimension %q", key)
+	}
+}
+
+// groupKey returns the key of the group the cost falls into, tag groups are
+// keyed like "<key>$<value>".
+func (c Cost) groupKey(groupBy *costexplorer.GroupDefinition) (string, error) {
+	key := aws.StringValue(groupBy.Key)
+
+	switch aws.StringValue(groupBy.Type) {
+	case costexplorer.GroupDefinitionTypeTag:
+		return key + "$" + c.Tags[key], nil
+	case costexplorer.GroupDefinitionTypeCostCategory:
+		return key + "$" + c.CostCategories[key], nil
+	default:
+		return c.dimension(key)
+	}
+}
+
+func (c Cost) matches(expression *costexplorer.Expression) (bool, error) {
+	if expression == nil {
+		return true, nil
+	}
+
+	switch {
+	case len(expression.And) > 0:
+		for _, e := range expression.And {
+			match, err := c.matches(e)
+			if err != nil || !match {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case len(expression.Or) > 0:
+		for _, e := range expression.Or {
+			match, err := c.matches(e)
+			if err != nil || match {
+				return match, err
+			}
+		}
+		return false, nil
+
+	case expression.Not != nil:
+		match, err := c.matches(expression.Not)
+		return !match, err
+
+	case expression.Dimensions != nil:
+		value, err := c.dimension(aws.StringValue(expression.Dimensions.Key))
+		if err != nil {
+			return false, err
+		}
+		for _, v := range expression.Dimensions.Values {
+			if aws.StringValue(v) == value {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case expression.Tags != nil:
+		value := c.Tags[aws.StringValue(expression.Tags.Key)]
+		if slices.Contains(aws.StringValueSlice(expression.Tags.MatchOptions), costexplorer.MatchOptionAbsent) {
+			return value == "", nil
+		}
+		return slices.Contains(aws.StringValueSlice(expression.Tags.Values), value), nil
+
+	case expression.CostCategories != nil:
+		value := c.CostCategories[aws.StringValue(expression.CostCategories.Key)]
+		if slices.Contains(aws.StringValueSlice(expression.CostCategories.MatchOptions), costexplorer.MatchOptionAbsent) {
+			return value == "", nil
+		}
+		return slices.Contains(aws.StringValueSlice(expression.CostCategories.Values), value), nil
+
+	default:
+		return false, fmt.Errorf("unsupported expression %s", expression)
+	}
+}
+
+// matchesAccount evaluates the account dimension of an expression, forecasts
+// are only known per account so other dimensions are ignored.
+func matchesAccount(expression *costexplorer.Expression, accountId string) bool {
+	if expression == nil {
+		return true
+	}
+
+	switch {
+	case len(expression.And) > 0:
+		for _, e := range expression.And {
+			if !matchesAccount(e, accountId) {
+				return false
+			}
+		}
+		return true
+
+	case len(expression.Or) > 0:
+		for _, e := range expression.Or {
+			if matchesAccount(e, accountId) {
+				return true
+			}
+		}
+		return false
+
+	case expression.Not != nil:
+		return !matchesAccount(expression.Not, accountId)
+
+	case expression.Dimensions != nil && aws.StringValue(expression.Dimensions.Key) == costexplorer.DimensionLinkedAccount:
+		return slices.Contains(aws.StringValueSlice(expression.Dimensions.Values), accountId)
+
+	default:
+		return true
+	}
+}
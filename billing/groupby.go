@@ -0,0 +1,146 @@
+package billing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// ParseGroupBy parses a dimension name like "service" or "usage-type", a tag
+// given as tag:<key> or a cost category given as costcategory:<name> into a
+// Cost Explorer group definition.
+func ParseGroupBy(s string) (*costexplorer.GroupDefinition, error) {
+	prefix, name, found := strings.Cut(s, ":")
+
+	switch {
+	case found && strings.EqualFold(prefix, "tag"):
+		if name == "" {
+			return nil, fmt.Errorf("missing tag key in %q", s)
+		}
+
+		return &costexplorer.GroupDefinition{
+			Type: aws.String(costexplorer.GroupDefinitionTypeTag),
+			Key:  aws.String(name),
+		}, nil
+
+	case found && (strings.EqualFold(prefix, "costcategory") || strings.EqualFold(prefix, "cc")):
+		if name == "" {
+			return nil, fmt.Errorf("missing cost category name in %q", s)
+		}
+
+		return &costexplorer.GroupDefinition{
+			Type: aws.String(costexplorer.GroupDefinitionTypeCostCategory),
+			Key:  aws.String(name),
+		}, nil
+
+	case found:
+		return nil, fmt.Errorf("unknown group by %q, expected a dimension, tag:<key> or costcategory:<name>", s)
+
+	default:
+		key, err := DimensionKey(s)
+		if err != nil {
+			return nil, err
+		}
+
+		return &costexplorer.GroupDefinition{
+			Type: aws.String(costexplorer.GroupDefinitionTypeDimension),
+			Key:  aws.String(key),
+		}, nil
+	}
+}
+
+// GroupValue returns the value of a group key as returned by Cost Explorer.
+// Keys of tag and cost category groups look like "<key>$<value>", an empty
+// value means the costs are not tagged or categorized.
+func GroupValue(definition *costexplorer.GroupDefinition, key string) string {
+	if aws.StringValue(definition.Type) == costexplorer.GroupDefinitionTypeDimension {
+		return key
+	}
+
+	_, value, found := strings.Cut(key, "$")
+	if !found {
+		return key
+	}
+
+	return value
+}
+
+// GetTags follows NextPageToken and returns the tag keys, or the values of a
+// tag key if one is given.
+func GetTags(src CostSource, input *costexplorer.GetTagsInput) ([]string, error) {
+	in := *input
+	in.NextPageToken = nil
+
+	tags := []string{}
+
+	for {
+		page, err := src.GetTags(&in)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, aws.StringValueSlice(page.Tags)...)
+
+		if aws.StringValue(page.NextPageToken) == "" {
+			break
+		}
+		in.NextPageToken = page.NextPageToken
+	}
+
+	return tags, nil
+}
+
+// GetCostCategories follows NextPageToken and returns the cost category
+// names, or the values of a cost category if one is given.
+func GetCostCategories(src CostSource, input *costexplorer.GetCostCategoriesInput) ([]string, error) {
+	in := *input
+	in.NextPageToken = nil
+
+	categories := []string{}
+
+	for {
+		page, err := src.GetCostCategories(&in)
+		if err != nil {
+			return nil, err
+		}
+
+		if in.CostCategoryName != nil {
+			categories = append(categories, aws.StringValueSlice(page.CostCategoryValues)...)
+		} else {
+			categories = append(categories, aws.StringValueSlice(page.CostCategoryNames)...)
+		}
+
+		if aws.StringValue(page.NextPageToken) == "" {
+			break
+		}
+		in.NextPageToken = page.NextPageToken
+	}
+
+	return categories, nil
+}
+
+// ListCostCategoryDefinitions follows NextToken and returns the references of
+// all cost categories.
+func ListCostCategoryDefinitions(src CostSource) ([]*costexplorer.CostCategoryReference, error) {
+	input := &costexplorer.ListCostCategoryDefinitionsInput{}
+
+	references := []*costexplorer.CostCategoryReference{}
+
+	for {
+		page, err := src.ListCostCategoryDefinitions(input)
+		if err != nil {
+			return nil, err
+		}
+
+		references = append(references, page.CostCategoryReferences...)
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	return references, nil
+}
@@ -0,0 +1,190 @@
+package billing
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/organizations"
+)
+
+// ListAccounts follows NextToken and returns the accounts of all pages.
+func ListAccounts(src AccountsSource) ([]*organizations.Account, error) {
+	input := &organizations.ListAccountsInput{}
+
+	accounts := []*organizations.Account{}
+
+	for {
+		page, err := src.ListAccounts(input)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, page.Accounts...)
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	return accounts, nil
+}
+
+// ListTagsForResource follows NextToken and returns the tags of an account,
+// organizational unit or root as a map.
+func ListTagsForResource(src AccountsSource, resourceId string) (map[string]string, error) {
+	input := &organizations.ListTagsForResourceInput{
+		ResourceId: aws.String(resourceId),
+	}
+
+	tags := map[string]string{}
+
+	for {
+		page, err := src.ListTagsForResource(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range page.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	return tags, nil
+}
+
+// OrganizationalUnit is a node of the organization tree. The root of the
+// organization is represented as an organizational unit as well.
+type OrganizationalUnit struct {
+	Id       string
+	Name     string
+	Parent   *OrganizationalUnit
+	Children []*OrganizationalUnit
+	Accounts []*organizations.Account
+}
+
+// Path returns the names of the units from the root down to this one.
+func (ou *OrganizationalUnit) Path() []string {
+	if ou.Parent == nil {
+		return []string{ou.Name}
+	}
+
+	return append(ou.Parent.Path(), ou.Name)
+}
+
+// Walk calls fn for the unit and all units below it, parents first.
+func (ou *OrganizationalUnit) Walk(fn func(*OrganizationalUnit)) {
+	fn(ou)
+
+	for _, child := range ou.Children {
+		child.Walk(fn)
+	}
+}
+
+// AccountIds returns the ids of the accounts in the unit and all units below
+// it.
+func (ou *OrganizationalUnit) AccountIds() []string {
+	ids := []string{}
+
+	ou.Walk(func(u *OrganizationalUnit) {
+		for _, account := range u.Accounts {
+			ids = append(ids, aws.StringValue(account.Id))
+		}
+	})
+
+	return ids
+}
+
+// DescribeOrganizationTree returns the root of the organization with all
+// organizational units and accounts below it.
+func DescribeOrganizationTree(src AccountsSource) (*OrganizationalUnit, error) {
+	input := &organizations.ListRootsInput{}
+
+	roots := []*organizations.Root{}
+
+	for {
+		page, err := src.ListRoots(input)
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, page.Roots...)
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("organization has no root")
+	}
+
+	root := &OrganizationalUnit{
+		Id:   aws.StringValue(roots[0].Id),
+		Name: aws.StringValue(roots[0].Name),
+	}
+
+	if err := describeChildren(src, root); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func describeChildren(src AccountsSource, parent *OrganizationalUnit) error {
+	accountsInput := &organizations.ListAccountsForParentInput{
+		ParentId: aws.String(parent.Id),
+	}
+
+	for {
+		page, err := src.ListAccountsForParent(accountsInput)
+		if err != nil {
+			return err
+		}
+
+		parent.Accounts = append(parent.Accounts, page.Accounts...)
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		accountsInput.NextToken = page.NextToken
+	}
+
+	unitsInput := &organizations.ListOrganizationalUnitsForParentInput{
+		ParentId: aws.String(parent.Id),
+	}
+
+	for {
+		page, err := src.ListOrganizationalUnitsForParent(unitsInput)
+		if err != nil {
+			return err
+		}
+
+		for _, unit := range page.OrganizationalUnits {
+			child := &OrganizationalUnit{
+				Id:     aws.StringValue(unit.Id),
+				Name:   aws.StringValue(unit.Name),
+				Parent: parent,
+			}
+
+			if err := describeChildren(src, child); err != nil {
+				return err
+			}
+
+			parent.Children = append(parent.Children, child)
+		}
+
+		if aws.StringValue(page.NextToken) == "" {
+			break
+		}
+		unitsInput.NextToken = page.NextToken
+	}
+
+	return nil
+}
@@ -2,259 +2,440 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
 	"sort"
-	"strings"
-	"sync"
-	"text/tabwriter"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/aws/aws-sdk-go/service/organizations"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 
+	"github.com/giantswarm/abu/billing"
 	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
 )
 
 var accountsCmd = &cobra.Command{
 	Use:   "accounts",
 	Short: "Print information on accounts",
-	Run:   runAccounts,
+	RunE:  runAccounts,
 }
 
+var (
+	accountsConfidence int
+)
+
 func init() {
+	accountsCmd.Flags().IntVar(&accountsConfidence, "confidence", 80, "Confidence level of the forecast prediction interval in percent, between 51 and 99")
+
+	addMetricFlag(accountsCmd)
+	addCostFilterFlags(accountsCmd)
+
 	rootCmd.AddCommand(accountsCmd)
 }
 
-func runAccounts(cmd *cobra.Command, args []string) {
-	result, err := organizationsSvc.ListAccounts(&organizations.ListAccountsInput{})
+// accountCost returns the cost of an account in the given period, limited
+// by the filter.
+func accountCost(accountId string, metric Metric, filter *costexplorer.Expression, period *costexplorer.DateInterval) (money.Amount, error) {
+	getCostAndUsageInput := &costexplorer.GetCostAndUsageInput{
+		Filter: billing.And(
+			&costexplorer.Expression{
+				Dimensions: &costexplorer.DimensionValues{
+					Key:    aws.String("LINKED_ACCOUNT"),
+					Values: []*string{aws.String(accountId)},
+				},
+			},
+			filter,
+		),
+		Granularity: aws.String("MONTHLY"),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{
+				Type: aws.String("DIMENSION"),
+				Key:  aws.String("LINKED_ACCOUNT"),
+			},
+		},
+		Metrics:    []*string{aws.String(metric.Usage)},
+		TimePeriod: period,
+	}
+
+	getCostAndUsageOutput, err := billing.GetCostAndUsage(backend.Costs, getCostAndUsageInput)
 	if err != nil {
-		log.Fatal(err)
+		return money.Amount{}, err
 	}
 
+	total := money.Zero(money.USD)
+
+	for _, resultByTime := range getCostAndUsageOutput.ResultsByTime {
+		for _, g := range resultByTime.Groups {
+			if *g.Keys[0] != accountId {
+				continue
+			}
+
+			dollar, err := money.CostExplorerGroupToDollar(g, metric.Usage)
+			if err != nil {
+				return money.Amount{}, err
+			}
+
+			total, err = total.Add(dollar)
+			if err != nil {
+				return money.Amount{}, err
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// accountLine holds the costs of an account, ForecastErr is set if Cost
+// Explorer could not forecast it.
+type accountLine struct {
+	Name   string
+	Id     string
+	Status string
+
+	Bill                amounts
+	MonthToDate         amounts
+	PreviousMonthToDate amounts
+	MonthToDateDelta    amounts
+
+	Forecast      amounts
+	ForecastLower amounts
+	ForecastUpper amounts
+	ForecastErr   error
+	Delta         amounts
+}
+
+// accountLines returns the costs of the accounts, fetched concurrently.
+func accountLines(accounts []*organizations.Account, metric Metric) ([]accountLine, error) {
+	now := backend.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	lastMonth := &costexplorer.DateInterval{
+		Start: aws.String(firstOfMonth.AddDate(0, -1, 0).Format("2006-01-02")),
+		End:   aws.String(firstOfMonth.Format("2006-01-02")),
+	}
+	monthToDate := &costexplorer.DateInterval{
+		Start: aws.String(firstOfMonth.Format("2006-01-02")),
+		End:   aws.String(today.Format("2006-01-02")),
+	}
+	// The same number of days at the start of last month, cut off at its
+	// end, to compare the month to date with.
+	previousMonthToDateEnd := firstOfMonth.AddDate(0, -1, today.Day()-1)
+	if previousMonthToDateEnd.After(firstOfMonth) {
+		previousMonthToDateEnd = firstOfMonth
+	}
+	previousMonthToDate := &costexplorer.DateInterval{
+		Start: lastMonth.Start,
+		End:   aws.String(previousMonthToDateEnd.Format("2006-01-02")),
+	}
+	restOfMonth := &costexplorer.DateInterval{
+		Start: aws.String(today.Format("2006-01-02")),
+		End:   aws.String(firstOfMonth.AddDate(0, 1, 0).Format("2006-01-02")),
+	}
+
+	filter, err := costFilter(&costexplorer.DateInterval{
+		Start: lastMonth.Start,
+		End:   restOfMonth.Start,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// CostInfo holds the costs of an account in dollars.
 	type CostInfo struct {
-		Id     string
-		Dollar float64
-		Euro   float64
+		Bill                money.Amount
+		MonthToDate         money.Amount
+		PreviousMonthToDate money.Amount
 	}
 
+	// ForecastInfo holds the forecast of the rest of the month in dollars. Err
+	// is set if Cost Explorer could not forecast the account.
 	type ForecastInfo struct {
-		Id     string
-		Dollar float64
-		Euro   float64
+		Mean  money.Amount
+		Lower money.Amount
+		Upper money.Amount
+		Err   error
 	}
 
-	costInfoChannel := make(chan CostInfo, len(result.Accounts))
-	forecastInfoChannel := make(chan ForecastInfo, len(result.Accounts))
+	costInfos := make([]CostInfo, len(accounts))
+	forecastInfos := make([]ForecastInfo, len(accounts))
 
-	var wg sync.WaitGroup
+	var g errgroup.Group
+	g.SetLimit(MAX_CONCURRENCY)
 
-	for _, account := range result.Accounts {
-		wg.Add(1)
-
-		go func(account *organizations.Account, costInfoChannel chan CostInfo) {
-			defer wg.Done()
+	for i, account := range accounts {
+		i, account := i, account
 
+		g.Go(func() error {
 			costInfo := CostInfo{
-				Id: *account.Id,
+				MonthToDate:         money.Zero(money.USD),
+				PreviousMonthToDate: money.Zero(money.USD),
 			}
 
-			getCostAndUsageInput := &costexplorer.GetCostAndUsageInput{
-				Filter: &costexplorer.Expression{
-					Dimensions: &costexplorer.DimensionValues{
-						Key:    aws.String("LINKED_ACCOUNT"),
-						Values: []*string{account.Id},
-					},
-				},
-				Granularity: aws.String("MONTHLY"),
-				GroupBy: []*costexplorer.GroupDefinition{
-					{
-						Type: aws.String("DIMENSION"),
-						Key:  aws.String("LINKED_ACCOUNT"),
-					},
-				},
-				Metrics: []*string{aws.String("UnblendedCost")},
-				TimePeriod: &costexplorer.DateInterval{
-					Start: aws.String(time.Now().AddDate(0, -1, -time.Now().Day()+1).Format("2006-01-02")),
-					End:   aws.String(time.Now().AddDate(0, 0, -time.Now().Day()+1).Format("2006-01-02")),
-				},
-			}
+			var err error
 
-			getCostAndUsageOutput, err := costExplorerSvc.GetCostAndUsage(getCostAndUsageInput)
+			costInfo.Bill, err = accountCost(*account.Id, metric, filter, lastMonth)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 
-			var group *costexplorer.Group
+			// On the first of the month there is nothing to compare yet.
+			if today.After(firstOfMonth) {
+				costInfo.MonthToDate, err = accountCost(*account.Id, metric, filter, monthToDate)
+				if err != nil {
+					return err
+				}
 
-			for _, resultByTime := range getCostAndUsageOutput.ResultsByTime {
-				for _, g := range resultByTime.Groups {
-					accountId := *g.Keys[0]
-					if accountId == *account.Id {
-						group = g
-					}
+				costInfo.PreviousMonthToDate, err = accountCost(*account.Id, metric, filter, previousMonthToDate)
+				if err != nil {
+					return err
 				}
 			}
 
-			dollar, err := money.CostExplorerGroupToDollar(group)
-			if err != nil {
-				log.Fatal(err)
+			costInfos[i] = costInfo
+
+			return nil
+		})
+
+		g.Go(func() error {
+			getCostForecastInput := &costexplorer.GetCostForecastInput{
+				Filter: billing.And(
+					&costexplorer.Expression{
+						Dimensions: &costexplorer.DimensionValues{
+							Key:    aws.String("LINKED_ACCOUNT"),
+							Values: []*string{account.Id},
+						},
+					},
+					filter,
+				),
+				Granularity:             aws.String("MONTHLY"),
+				Metric:                  aws.String(metric.Forecast),
+				PredictionIntervalLevel: aws.Int64(int64(accountsConfidence)),
+				TimePeriod:              restOfMonth,
 			}
 
-			euro, err := money.CostExplorerGroupToEuro(group)
+			getCostForecastOutput, err := backend.Forecasts.GetCostForecast(getCostForecastInput)
 			if err != nil {
-				log.Fatal(err)
+				// Accounts without enough history cannot be forecast, which
+				// is no reason to fail the whole report.
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == costexplorer.ErrCodeDataUnavailableException {
+					forecastInfos[i] = ForecastInfo{Err: err}
+					return nil
+				}
+				return err
 			}
 
-			costInfo.Dollar = dollar
-			costInfo.Euro = euro
+			if len(getCostForecastOutput.ForecastResultsByTime) == 0 {
+				forecastInfos[i] = ForecastInfo{Err: fmt.Errorf("Cost Explorer returned no forecast")}
+				return nil
+			}
 
-			costInfoChannel <- costInfo
-		}(account, costInfoChannel)
+			forecastResult := getCostForecastOutput.ForecastResultsByTime[0]
 
-		wg.Add(1)
+			var forecastInfo ForecastInfo
 
-		go func(account *organizations.Account, ch chan ForecastInfo) {
-			defer wg.Done()
+			forecastInfo.Mean, err = money.ForecastResultToDollar(forecastResult)
+			if err != nil {
+				return err
+			}
 
-			forecastInfo := ForecastInfo{
-				Id: *account.Id,
+			forecastInfo.Lower, err = money.ForecastLowerBoundToDollar(forecastResult)
+			if err != nil {
+				return err
 			}
 
-			getCostForecastInput := &costexplorer.GetCostForecastInput{
-				Filter: &costexplorer.Expression{
-					Dimensions: &costexplorer.DimensionValues{
-						Key:    aws.String("LINKED_ACCOUNT"),
-						Values: []*string{account.Id},
-					},
-				},
-				Granularity: aws.String("MONTHLY"),
-				Metric:      aws.String("UNBLENDED_COST"),
-				TimePeriod: &costexplorer.DateInterval{
-					Start: aws.String(time.Now().Format("2006-01-02")),
-					End:   aws.String(time.Now().AddDate(0, 0, 1).Format("2006-01-02")),
-				},
+			forecastInfo.Upper, err = money.ForecastUpperBoundToDollar(forecastResult)
+			if err != nil {
+				return err
 			}
 
-			getCostForecastOutput, err := costExplorerSvc.GetCostForecast(getCostForecastInput)
+			forecastInfos[i] = forecastInfo
 
-			if err == nil {
-				dollar, err := money.ForecastResultToDollar(getCostForecastOutput.ForecastResultsByTime[0])
-				if err != nil {
-					log.Fatal(err)
-				}
+			return nil
+		})
+	}
 
-				euro, err := money.ForecastResultToEuro(getCostForecastOutput.ForecastResultsByTime[0])
-				if err != nil {
-					log.Fatal(err)
-				}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
-				forecastInfo.Dollar = dollar
-				forecastInfo.Euro = euro
-			}
+	// fullMonth adds the month to date to a forecast of the rest of the month.
+	fullMonth := func(monthToDate money.Amount, restOfMonth money.Amount) (amounts, error) {
+		dollar, err := monthToDate.Add(restOfMonth)
+		if err != nil {
+			return nil, err
+		}
 
-			forecastInfoChannel <- forecastInfo
-		}(account, forecastInfoChannel)
+		return convert(dollar, nil)
 	}
 
-	go func() {
-		wg.Wait()
+	lines := []accountLine{}
+	for i, account := range accounts {
+		line := accountLine{
+			Name:   *account.Name,
+			Id:     *account.Id,
+			Status: *account.Status,
+		}
 
-		close(costInfoChannel)
-		close(forecastInfoChannel)
-	}()
+		costInfo := costInfos[i]
 
-	costInfos := []CostInfo{}
-	forecastInfos := []ForecastInfo{}
+		line.Bill, err = convert(costInfo.Bill, lastMonth)
+		if err != nil {
+			return nil, err
+		}
+		line.MonthToDate, err = convert(costInfo.MonthToDate, monthToDate)
+		if err != nil {
+			return nil, err
+		}
+		line.PreviousMonthToDate, err = convert(costInfo.PreviousMonthToDate, previousMonthToDate)
+		if err != nil {
+			return nil, err
+		}
 
-	for costInfo := range costInfoChannel {
-		costInfos = append(costInfos, costInfo)
-	}
-	for forecastInfo := range forecastInfoChannel {
-		forecastInfos = append(forecastInfos, forecastInfo)
-	}
+		line.MonthToDateDelta, err = line.MonthToDate.sub(line.PreviousMonthToDate)
+		if err != nil {
+			return nil, err
+		}
 
-	type Line struct {
-		Name      string
-		Id        string
-		Suspended string
+		forecastInfo := forecastInfos[i]
 
-		Dollar float64
-		Euro   float64
+		line.ForecastErr = forecastInfo.Err
+		if line.ForecastErr == nil {
+			line.Forecast, err = fullMonth(costInfo.MonthToDate, forecastInfo.Mean)
+			if err != nil {
+				return nil, err
+			}
+			line.ForecastLower, err = fullMonth(costInfo.MonthToDate, forecastInfo.Lower)
+			if err != nil {
+				return nil, err
+			}
+			line.ForecastUpper, err = fullMonth(costInfo.MonthToDate, forecastInfo.Upper)
+			if err != nil {
+				return nil, err
+			}
 
-		DollarForecast float64
-		EuroForecast   float64
+			line.Delta, err = line.Forecast.sub(line.Bill)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-		DollarDelta float64
-		EuroDelta   float64
+		lines = append(lines, line)
 	}
 
-	lines := []Line{}
-	for _, account := range result.Accounts {
-		line := Line{
-			Name:      *account.Name,
-			Id:        *account.Id,
-			Suspended: "NO",
-		}
+	return lines, nil
+}
 
-		if *account.Status == "SUSPENDED" {
-			line.Suspended = "YES"
-		}
+func runAccounts(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
 
-		for _, costInfo := range costInfos {
-			if costInfo.Id == line.Id {
-				line.Dollar = costInfo.Dollar
-				line.Euro = costInfo.Euro
-			}
-		}
-		for _, forecastInfo := range forecastInfos {
-			if forecastInfo.Id == line.Id {
-				line.DollarForecast = forecastInfo.Dollar
-				line.EuroForecast = forecastInfo.Euro
-			}
-		}
+	metric, err := selectedMetric()
+	if err != nil {
+		return err
+	}
 
-		line.DollarDelta = line.DollarForecast - line.Dollar
-		line.EuroDelta = line.EuroForecast - line.Euro
+	if accountsConfidence < 51 || accountsConfidence > 99 {
+		return fmt.Errorf("--confidence must be between 51 and 99, got %d", accountsConfidence)
+	}
 
-		lines = append(lines, line)
+	accounts, err := billing.ListAccounts(backend.Accounts)
+	if err != nil {
+		return err
 	}
 
+	accounts, err = filterAccountList(accounts)
+	if err != nil {
+		return err
+	}
+
+	lines, err := accountLines(accounts, metric)
+	if err != nil {
+		return err
+	}
+
+	forecastLowerColumns := MoneyColumns{Key: "forecast_lower_bound", Title: fmt.Sprintf("%s %d%%", FORECAST_LOWER, accountsConfidence)}
+	forecastUpperColumns := MoneyColumns{Key: "forecast_upper_bound", Title: fmt.Sprintf("%s %d%%", FORECAST_UPPER, accountsConfidence)}
+
 	sort.Slice(lines, func(i, j int) bool {
 		return lines[i].Name < lines[j].Name
 	})
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	columns := []output.Column{NAME_COLUMN, ID_COLUMN}
+	columns = append(columns, BILL_COLUMNS.Columns()...)
+	columns = append(columns, MONTH_TO_DATE_COLUMNS.Columns()...)
+	columns = append(columns, PREVIOUS_MONTH_TO_DATE_COLUMNS.Columns()...)
+	columns = append(columns, MONTH_TO_DATE_DELTA_COLUMNS.Columns()...)
+	columns = append(columns, FORECAST_COLUMNS.Columns()...)
+	columns = append(columns, forecastLowerColumns.Columns()...)
+	columns = append(columns, forecastUpperColumns.Columns()...)
+	columns = append(columns, BILL_FORECAST_DELTA_COLUMNS.Columns()...)
+	columns = append(columns, SUSPENDED_COLUMN)
+
+	t := output.NewTable(columns...)
+
+	for _, line := range lines {
+		cells := []output.Cell{textCell(line.Name), textCell(line.Id)}
+		cells = append(cells, moneyCells(line.Bill)...)
+		cells = append(cells, moneyCells(line.MonthToDate)...)
+		cells = append(cells, moneyCells(line.PreviousMonthToDate)...)
+		cells = append(cells, moneyCells(line.MonthToDateDelta)...)
+		if line.ForecastErr == nil {
+			cells = append(cells, moneyCells(line.Forecast)...)
+			cells = append(cells, moneyCells(line.ForecastLower)...)
+			cells = append(cells, moneyCells(line.ForecastUpper)...)
+			cells = append(cells, moneyCells(line.Delta)...)
+		} else {
+			for i := 0; i < 4; i++ {
+				cells = append(cells, missingMoneyCells()...)
+			}
+		}
+		cells = append(cells, suspendedCell(line.Status))
+
+		t.AddRow(cells...)
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+	writeUnforecastable(cmd, lines)
+
+	writeExchangeRate(cmd)
 
-	fmt.Fprintln(w, strings.Join([]string{
-		NAME_TITLE,
-		ID_TITLE,
-		BILL_DOLLAR_TITLE,
-		BILL_ESTIMATED_EURO_TITLE,
-		FORECAST_DOLLAR_TITLE,
-		FORECAST_ESTIMATED_EURO_TITLE,
-		BILL_FORECAST_DELTA_DOLLAR_TITLE,
-		BILL_FORECAST_DELTA_ESTIMATED_EURO_TITLE,
-		SUSPENDED_TITLE,
-	}, "\t"))
+	return nil
+}
 
+// writeUnforecastable lists the accounts Cost Explorer could not forecast,
+// after the table or on stderr for structured output.
+func writeUnforecastable(cmd *cobra.Command, lines []accountLine) {
+	unforecastable := []accountLine{}
 	for _, line := range lines {
-		s := fmt.Sprintf(
-			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
-			line.Name,
-			line.Id,
-			money.Float64DollarToStringDollar(line.Dollar),
-			money.Float64EuroToStringEuro(line.Euro),
-			money.Float64DollarToStringDollar(line.DollarForecast),
-			money.Float64EuroToStringEuro(line.EuroForecast),
-			money.Float64DollarToStringDollar(line.DollarDelta),
-			money.Float64EuroToStringEuro(line.EuroDelta),
-			line.Suspended,
-		)
-		fmt.Fprintln(w, s)
+		if line.ForecastErr != nil {
+			unforecastable = append(unforecastable, line)
+		}
 	}
 
-	w.Flush()
+	if len(unforecastable) == 0 {
+		return
+	}
+
+	w := cmd.OutOrStdout()
+	if outputFormat != output.FormatTable {
+		w = cmd.ErrOrStderr()
+	} else {
+		fmt.Fprintln(w)
+	}
+
+	for _, line := range unforecastable {
+		reason := line.ForecastErr.Error()
+		if aerr, ok := line.ForecastErr.(awserr.Error); ok {
+			reason = aerr.Message()
+		}
+
+		fmt.Fprintf(w, "No forecast for %s (%s): %s\n", line.Name, line.Id, reason)
+	}
 }
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
+)
+
+var anomaliesCmd = &cobra.Command{
+	Use:   "anomalies",
+	Short: "Print days with unusual costs",
+	Long: `Print days with unusual costs.
+
+The daily cost of every account and service is compared with the days in the
+window before it. A day is anomalous if its cost lies further from the median
+than --sensitivity times the median absolute deviation, or with
+--method=stddev further from the mean than --sensitivity times the standard
+deviation.`,
+	RunE: runAnomalies,
+}
+
+var (
+	anomaliesBy          []string
+	anomaliesDays        int
+	anomaliesWindow      int
+	anomaliesMethod      string
+	anomaliesSensitivity float64
+	anomaliesMinDelta    float64
+	anomaliesDrops       bool
+)
+
+var anomaliesMethods = []string{"mad", "stddev"}
+
+func init() {
+	anomaliesCmd.Flags().StringSliceVar(&anomaliesBy, "by", []string{"account", "service"}, "Look for anomalies by up to two dimensions, tags given as tag:<key> or cost categories given as costcategory:<name>")
+	anomaliesCmd.Flags().IntVar(&anomaliesDays, "days", 14, "Number of complete days to look for anomalies in")
+	anomaliesCmd.Flags().IntVar(&anomaliesWindow, "window", 28, "Number of days before each day its expected cost is taken from")
+	anomaliesCmd.Flags().StringVar(&anomaliesMethod, "method", "mad", "Detector, one of mad (median absolute deviation), stddev (mean and standard deviation)")
+	anomaliesCmd.Flags().Float64Var(&anomaliesSensitivity, "sensitivity", 3, "Number of deviations a cost must be away from the expected cost, lower finds more anomalies")
+	anomaliesCmd.Flags().Float64Var(&anomaliesMinDelta, "min-delta", 1, "Leave out anomalies less than this many dollars away from the expected cost")
+	anomaliesCmd.Flags().BoolVar(&anomaliesDrops, "drops", false, "Include days with unusually low costs")
+
+	addMetricFlag(anomaliesCmd)
+	addCostFilterFlags(anomaliesCmd)
+
+	rootCmd.AddCommand(anomaliesCmd)
+}
+
+func median(values []decimal.Decimal) decimal.Decimal {
+	sorted := slices.Clone(values)
+	slices.SortFunc(sorted, decimal.Decimal.Cmp)
+
+	if len(sorted)%2 == 0 {
+		return sorted[len(sorted)/2-1].Add(sorted[len(sorted)/2]).Div(decimal.New(2, 0))
+	}
+
+	return sorted[len(sorted)/2]
+}
+
+// anomalyScore returns the cost expected from the history and how many
+// deviations the value lies away from it. The score is infinite if the
+// history does not vary but the value differs from it.
+func anomalyScore(history []money.Amount, value money.Amount) (money.Amount, float64) {
+	values := []decimal.Decimal{}
+	for _, amount := range history {
+		values = append(values, amount.Decimal())
+	}
+
+	var expected decimal.Decimal
+	var spread float64
+
+	switch anomaliesMethod {
+	case "stddev":
+		sum := decimal.Zero
+		for _, v := range values {
+			sum = sum.Add(v)
+		}
+		expected = sum.DivRound(decimal.New(int64(len(values)), 0), 2)
+
+		variance := decimal.Zero
+		for _, v := range values {
+			variance = variance.Add(v.Sub(expected).Mul(v.Sub(expected)))
+		}
+		variance = variance.DivRound(decimal.New(int64(len(values)), 0), 8)
+
+		f, _ := variance.Float64()
+		spread = math.Sqrt(f)
+
+	default:
+		expected = median(values)
+
+		deviations := []decimal.Decimal{}
+		for _, v := range values {
+			deviations = append(deviations, v.Sub(expected).Abs())
+		}
+
+		// Scaled to match the standard deviation of normally distributed
+		// costs, so the sensitivity means about the same for both methods.
+		f, _ := median(deviations).Float64()
+		spread = 1.4826 * f
+	}
+
+	delta, _ := value.Decimal().Sub(expected).Float64()
+
+	if spread == 0 {
+		if delta == 0 {
+			return money.Dollar(expected), 0
+		}
+		return money.Dollar(expected), math.Copysign(math.Inf(1), delta)
+	}
+
+	return money.Dollar(expected), delta / spread
+}
+
+func scoreCell(score float64) output.Cell {
+	if math.IsInf(score, 0) {
+		if score > 0 {
+			return output.Cell{Text: "+∞"}
+		}
+		return output.Cell{Text: "-∞"}
+	}
+
+	text := strconv.FormatFloat(score, 'f', 1, 64)
+	if score > 0 {
+		text = "+" + text
+	}
+
+	return output.Cell{
+		Text:  strings.Replace(text, ".", money.CurrentLocale().Decimal, 1),
+		Value: output.Number(strconv.FormatFloat(score, 'f', 2, 64)),
+	}
+}
+
+func runAnomalies(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	metric, err := selectedMetric()
+	if err != nil {
+		return err
+	}
+
+	groupBy, err := parseGroupByList(anomaliesBy)
+	if err != nil {
+		return err
+	}
+
+	if len(groupBy) > 2 {
+		return fmt.Errorf("--by takes at most two keys")
+	}
+	if !slices.Contains(anomaliesMethods, anomaliesMethod) {
+		return fmt.Errorf("unknown method %q, must be one of %s", anomaliesMethod, strings.Join(anomaliesMethods, ", "))
+	}
+	if anomaliesDays < 1 {
+		return fmt.Errorf("--days must be at least 1")
+	}
+	if anomaliesWindow < 2 {
+		return fmt.Errorf("--window must be at least 2")
+	}
+	if anomaliesSensitivity <= 0 {
+		return fmt.Errorf("--sensitivity must be positive")
+	}
+
+	// Today is left out, as its costs are still coming in.
+	now := backend.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	period := &costexplorer.DateInterval{
+		Start: aws.String(today.AddDate(0, 0, -anomaliesDays-anomaliesWindow).Format("2006-01-02")),
+		End:   aws.String(today.Format("2006-01-02")),
+	}
+
+	filter, err := costFilter(period)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := billing.ListAccounts(backend.Accounts)
+	if err != nil {
+		return err
+	}
+
+	accountNames := map[string]string{}
+	for _, account := range accounts {
+		accountNames[*account.Id] = *account.Name
+	}
+
+	getCostAndUsageOutput, err := billing.GetCostAndUsage(backend.Costs, &costexplorer.GetCostAndUsageInput{
+		Filter:      filter,
+		Granularity: aws.String("DAILY"),
+		GroupBy:     groupBy,
+		Metrics:     []*string{aws.String(metric.Usage)},
+		TimePeriod:  period,
+	})
+	if err != nil {
+		return err
+	}
+
+	resultsByTime := getCostAndUsageOutput.ResultsByTime
+
+	// Costs per group and day, zero on days a group has no costs.
+	costs := map[string][]money.Amount{}
+	keys := map[string][]string{}
+
+	for i, resultByTime := range resultsByTime {
+		for _, group := range resultByTime.Groups {
+			key := strings.Join(aws.StringValueSlice(group.Keys), "\x00")
+
+			if _, ok := costs[key]; !ok {
+				costs[key] = make([]money.Amount, len(resultsByTime))
+				for j := range costs[key] {
+					costs[key][j] = money.Zero(money.USD)
+				}
+				keys[key] = aws.StringValueSlice(group.Keys)
+			}
+
+			dollar, err := money.CostExplorerGroupToDollar(group, metric.Usage)
+			if err != nil {
+				return err
+			}
+			costs[key][i] = dollar
+		}
+	}
+
+	type Anomaly struct {
+		Keys     []string
+		Day      int
+		Expected money.Amount
+		Actual   money.Amount
+		Delta    decimal.Decimal
+		Score    float64
+	}
+
+	minDelta := decimal.NewFromFloat(anomaliesMinDelta)
+
+	anomalies := []Anomaly{}
+	for key, values := range costs {
+		for i := anomaliesWindow; i < len(values); i++ {
+			expected, score := anomalyScore(values[i-anomaliesWindow:i], values[i])
+
+			delta := values[i].Decimal().Sub(expected.Decimal())
+
+			if math.Abs(score) < anomaliesSensitivity || delta.Abs().Cmp(minDelta) < 0 {
+				continue
+			}
+			if score < 0 && !anomaliesDrops {
+				continue
+			}
+
+			anomalies = append(anomalies, Anomaly{
+				Keys:     keys[key],
+				Day:      i,
+				Expected: expected,
+				Actual:   values[i],
+				Delta:    delta,
+				Score:    score,
+			})
+		}
+	}
+
+	// Latest first, and the largest deviations first within a day.
+	slices.SortFunc(anomalies, func(a, b Anomaly) int {
+		if a.Day != b.Day {
+			return b.Day - a.Day
+		}
+		return b.Delta.Abs().Cmp(a.Delta.Abs())
+	})
+
+	columns := []output.Column{DATE_COLUMN}
+	for _, definition := range groupBy {
+		if isAccountGroup(definition) {
+			columns = append(columns, NAME_COLUMN, ID_COLUMN)
+		} else {
+			columns = append(columns, groupColumn(definition))
+		}
+	}
+	columns = append(columns, EXPECTED_COLUMNS.Columns()...)
+	columns = append(columns, ACTUAL_COLUMNS.Columns()...)
+	columns = append(columns, DELTA_COLUMNS.Columns()...)
+	columns = append(columns, SCORE_COLUMN)
+
+	t := output.NewTable(columns...)
+
+	for _, anomaly := range anomalies {
+		day := resultsByTime[anomaly.Day].TimePeriod
+
+		cells := []output.Cell{textCell(aws.StringValue(day.Start))}
+		for i, definition := range groupBy {
+			if isAccountGroup(definition) {
+				cells = append(cells, textCell(accountNames[anomaly.Keys[i]]), textCell(anomaly.Keys[i]))
+			} else {
+				cells = append(cells, groupCell(definition, anomaly.Keys[i]))
+			}
+		}
+
+		expected, err := convert(anomaly.Expected, day)
+		if err != nil {
+			return err
+		}
+		actual, err := convert(anomaly.Actual, day)
+		if err != nil {
+			return err
+		}
+
+		delta, err := actual.sub(expected)
+		if err != nil {
+			return err
+		}
+
+		cells = append(cells, moneyCells(expected)...)
+		cells = append(cells, moneyCells(actual)...)
+		cells = append(cells, moneyCells(delta)...)
+		cells = append(cells, scoreCell(anomaly.Score))
+
+		t.AddRow(cells...)
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+	writeExchangeRate(cmd)
+
+	return nil
+}
@@ -2,82 +2,221 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"strings"
-	"text/tabwriter"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/spf13/cobra"
 
+	"github.com/giantswarm/abu/billing"
 	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
 )
 
 var billsCmd = &cobra.Command{
 	Use:   "bills",
 	Short: "Print information on bills",
-	Run:   runBills,
+	RunE:  runBills,
 }
 
+var (
+	billsFrom           string
+	billsTo             string
+	billsMonths         int
+	billsGranularity    string
+	billsIncludeCurrent bool
+	billsGroupBy        string
+)
+
 func init() {
+	billsCmd.Flags().StringVar(&billsFrom, "from", "", "Start date (YYYY-MM-DD, inclusive), overrides --months")
+	billsCmd.Flags().StringVar(&billsTo, "to", "", "End date (YYYY-MM-DD, exclusive), defaults to the start of the current month")
+	billsCmd.Flags().IntVar(&billsMonths, "months", 6, "Number of complete months to show")
+	billsCmd.Flags().StringVar(&billsGranularity, "granularity", "monthly", "Granularity, one of daily, monthly")
+	billsCmd.Flags().BoolVar(&billsIncludeCurrent, "include-current", false, "Include the estimated current month to date")
+	billsCmd.Flags().StringVar(&billsGroupBy, "group-by", "", "Split the costs by a dimension like service, a tag given as tag:<key> or a cost category given as costcategory:<name>")
+
+	addMetricFlag(billsCmd)
+	addCostFilterFlags(billsCmd)
+
 	rootCmd.AddCommand(billsCmd)
 }
 
-func runBills(cmd *cobra.Command, args []string) {
+func billsTimePeriod(now time.Time) (*costexplorer.DateInterval, error) {
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	if billsMonths < 0 {
+		return nil, fmt.Errorf("--months must not be negative")
+	}
+
+	start := firstOfMonth.AddDate(0, -billsMonths, 0)
+	end := firstOfMonth
+	if billsIncludeCurrent {
+		end = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if billsFrom != "" {
+		from, err := time.Parse("2006-01-02", billsFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --from: %w", err)
+		}
+		start = from
+	}
+
+	if billsTo != "" {
+		to, err := time.Parse("2006-01-02", billsTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --to: %w", err)
+		}
+		end = to
+	}
+
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start date %s is not before end date %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	return &costexplorer.DateInterval{
+		Start: aws.String(start.Format("2006-01-02")),
+		End:   aws.String(end.Format("2006-01-02")),
+	}, nil
+}
+
+func runBills(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	metric, err := selectedMetric()
+	if err != nil {
+		return err
+	}
+
+	timePeriod, err := billsTimePeriod(backend.Now())
+	if err != nil {
+		return err
+	}
+
+	var granularity string
+	var periodColumn output.Column
+
+	switch billsGranularity {
+	case "daily":
+		granularity = "DAILY"
+		periodColumn = DATE_COLUMN
+	case "monthly":
+		granularity = "MONTHLY"
+		periodColumn = MONTH_COLUMN
+	default:
+		return fmt.Errorf("unknown granularity %q, must be one of daily, monthly", billsGranularity)
+	}
+
+	filter, err := costFilter(timePeriod)
+	if err != nil {
+		return err
+	}
+
 	input := &costexplorer.GetCostAndUsageInput{
-		TimePeriod: &costexplorer.DateInterval{
-			Start: aws.String(time.Now().AddDate(0, -6, -time.Now().Day()+1).Format("2006-01-02")),
-			End:   aws.String(time.Now().AddDate(0, 0, -time.Now().Day()+1).Format("2006-01-02")),
-		},
-		Granularity: aws.String("MONTHLY"),
-		Metrics:     []*string{aws.String("UnblendedCost")},
+		Filter:      filter,
+		TimePeriod:  timePeriod,
+		Granularity: aws.String(granularity),
+		Metrics:     []*string{aws.String(metric.Usage)},
+	}
+
+	var groupBy *costexplorer.GroupDefinition
+	if billsGroupBy != "" {
+		groupBy, err = billing.ParseGroupBy(billsGroupBy)
+		if err != nil {
+			return err
+		}
+
+		input.GroupBy = []*costexplorer.GroupDefinition{groupBy}
 	}
 
-	result, err := costExplorerSvc.GetCostAndUsage(input)
+	result, err := billing.GetCostAndUsage(backend.Costs, input)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	columns := []output.Column{periodColumn}
+	if groupBy != nil {
+		columns = append(columns, groupColumn(groupBy))
+	}
+	columns = append(columns, COST_COLUMNS.Columns()...)
+	if billsIncludeCurrent {
+		columns = append(columns, ESTIMATED_COLUMN)
+	}
 
-	fmt.Fprintln(w, strings.Join([]string{
-		MONTH_TITLE,
-		COST_DOLLAR_TITLE,
-		COST_ESTIMATED_EURO_TITLE,
-	}, "\t"))
+	t := output.NewTable(columns...)
+
+	// The end of the period is exclusive, so its year is taken a day before.
+	end, err := time.Parse("2006-01-02", *timePeriod.End)
+	if err != nil {
+		return err
+	}
+	withYear := (*timePeriod.Start)[:4] != end.AddDate(0, 0, -1).Format("2006")
 
 	for i := len(result.ResultsByTime) - 1; i >= 0; i-- {
 		resultByTime := result.ResultsByTime[i]
 
-		if *resultByTime.Estimated {
+		if *resultByTime.Estimated && !billsIncludeCurrent {
 			continue
 		}
 
-		month, err := AWSMonthToString(*resultByTime.TimePeriod.Start)
-		if err != nil {
-			log.Fatal(err)
+		periodCell := textCell(*resultByTime.TimePeriod.Start)
+		if granularity == "MONTHLY" {
+			periodCell, err = monthCell(*resultByTime.TimePeriod.Start, withYear)
+			if err != nil {
+				return err
+			}
 		}
 
-		dollar, err := money.CostExplorerResultByTimeToDollar(resultByTime)
-		if err != nil {
-			log.Fatal(err)
+		if groupBy == nil {
+			dollar, err := money.CostExplorerResultByTimeToDollar(resultByTime, metric.Usage)
+			if err != nil {
+				return err
+			}
+
+			cells := []output.Cell{periodCell}
+			converted, err := convert(dollar, resultByTime.TimePeriod)
+			if err != nil {
+				return err
+			}
+			cells = append(cells, moneyCells(converted)...)
+			if billsIncludeCurrent {
+				cells = append(cells, yesNoCell(*resultByTime.Estimated))
+			}
+
+			t.AddRow(cells...)
+
+			continue
 		}
 
-		euro, err := money.CostExplorerResultByTimeToEuro(resultByTime)
-		if err != nil {
-			log.Fatal(err)
+		sortGroups(groupBy, resultByTime.Groups)
+
+		for _, group := range resultByTime.Groups {
+			dollar, err := money.CostExplorerGroupToDollar(group, metric.Usage)
+			if err != nil {
+				return err
+			}
+
+			cells := []output.Cell{periodCell, groupCell(groupBy, *group.Keys[0])}
+			converted, err := convert(dollar, resultByTime.TimePeriod)
+			if err != nil {
+				return err
+			}
+			cells = append(cells, moneyCells(converted)...)
+			if billsIncludeCurrent {
+				cells = append(cells, yesNoCell(*resultByTime.Estimated))
+			}
+
+			t.AddRow(cells...)
 		}
+	}
 
-		s := fmt.Sprintf(
-			"%s\t%s\t%s",
-			month,
-			money.Float64DollarToStringDollar(dollar),
-			money.Float64EuroToStringEuro(euro),
-		)
-		fmt.Fprintln(w, s)
+	if err := writeTable(cmd, t); err != nil {
+		return err
 	}
+	writeExchangeRate(cmd)
 
-	w.Flush()
+	return nil
 }
@@ -2,104 +2,645 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"slices"
 	"strings"
-	"text/tabwriter"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/budgets"
 	"github.com/aws/aws-sdk-go/service/organizations"
 	"github.com/spf13/cobra"
 
+	"github.com/giantswarm/abu/billing"
 	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
 )
 
 var budgetCmd = &cobra.Command{
 	Use:   "budgets",
 	Short: "Print information on budgets",
-	Run:   runBudget,
+	RunE:  runBudget,
 }
 
 func init() {
 	rootCmd.AddCommand(budgetCmd)
 }
 
-func runBudget(cmd *cobra.Command, args []string) {
-	organizationResult, err := organizationsSvc.DescribeOrganization(&organizations.DescribeOrganizationInput{})
+func runBudget(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	accountId, err := budgetAccountId()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	budgetResult, err := budgetSvc.DescribeBudgets(&budgets.DescribeBudgetsInput{
-		AccountId: aws.String(*organizationResult.Organization.MasterAccountId),
-	})
+	budgetList, err := billing.DescribeBudgets(backend.Budgets, accountId)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	columns := []output.Column{NAME_COLUMN}
+	columns = append(columns, BUDGET_COLUMNS.Columns()...)
+	columns = append(columns, COST_COLUMNS.Columns()...)
+	columns = append(columns, FORECAST_COLUMNS.Columns()...)
+	columns = append(columns, BUDGET_FORECAST_DELTA_COLUMNS.Columns()...)
 
-	fmt.Fprintln(w, strings.Join([]string{
-		NAME_TITLE,
-		BUDGET_DOLLAR_TITLE,
-		BUDGET_ESTIMATED_EURO_TITLE,
-		COST_DOLLAR_TITLE,
-		COST_ESTIMATED_EURO_TITLE,
-		FORECAST_DOLLAR_TITLE,
-		FORECAST_ESTIMATED_EURO_TITLE,
-		BUDGET_FORECAST_DELTA_DOLLAR_TITLE,
-		BUDGET_FORECAST_DELTA_EURO_TITLE,
-	}, "\t"))
+	t := output.NewTable(columns...)
+
+	for _, budget := range budgetList {
+		// Usage and reservation budgets are kept in hours, gigabytes or
+		// percentages rather than money.
+		if budgetType := aws.StringValue(budget.BudgetType); budgetType != "" && budgetType != budgets.BudgetTypeCost {
+			continue
+		}
 
-	for _, budget := range budgetResult.Budgets {
 		limitDollar, err := money.BudgetLimitToDollar(budget)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		limitEuro, err := money.BudgetLimitToEuro(budget)
+		spendDollar, err := money.BudgetSpendToDollar(budget)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		spendDollar, err := money.BudgetSpendToDollar(budget)
+		forecastDollar, err := money.BudgetForecastToDollar(budget)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		spendEuro, err := money.BudgetSpendToEuro(budget)
+		limit, err := convert(limitDollar, nil)
+		if err != nil {
+			return err
+		}
+		spend, err := convert(spendDollar, nil)
+		if err != nil {
+			return err
+		}
+		forecast, err := convert(forecastDollar, nil)
+		if err != nil {
+			return err
+		}
+		forecastDelta, err := forecast.sub(limit)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		forecastDollar, err := money.BudgetForecastToDollar(budget)
+		cells := []output.Cell{textCell(*budget.BudgetName)}
+		cells = append(cells, moneyCells(limit)...)
+		cells = append(cells, moneyCells(spend)...)
+		cells = append(cells, moneyCells(forecast)...)
+		cells = append(cells, moneyCells(forecastDelta)...)
+
+		t.AddRow(cells...)
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+	writeExchangeRate(cmd)
+
+	return nil
+}
+
+var budgetCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a cost budget",
+	Long: `Create a cost budget.
+
+The budget covers the costs selected by --account, --ou, --exclude-account,
+--service, --region and --tag, or all costs if none of them are given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBudgetCreate,
+}
+
+var budgetUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Change the limit, period or filters of a cost budget",
+	Long: `Change the limit, period or filters of a cost budget.
+
+Only what is given changes. Giving any of --account, --ou, --exclude-account,
+--service, --region and --tag replaces all filters of the budget.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBudgetUpdate,
+}
+
+var budgetDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a budget",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBudgetDelete,
+}
+
+var (
+	budgetLimit         string
+	budgetLimitCurrency string
+	budgetPeriod        string
+	budgetTags          []string
+	budgetClearFilters  bool
+	budgetDryRun        bool
+)
+
+// budgetPeriods maps the values of --period to budget time units.
+var budgetPeriods = map[string]string{
+	"monthly":   budgets.TimeUnitMonthly,
+	"quarterly": budgets.TimeUnitQuarterly,
+	"annually":  budgets.TimeUnitAnnually,
+}
+
+// budgetFilterNames are the names of the cost filters budgets are created
+// with, in the order they are shown.
+var budgetFilterNames = []struct {
+	Key  string
+	Name string
+}{
+	{"LinkedAccount", "accounts"},
+	{"Service", "services"},
+	{"Region", "regions"},
+	{"TagKeyValue", "tags"},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{budgetCreateCmd, budgetUpdateCmd} {
+		c.Flags().StringVar(&budgetLimit, "limit", "", "Limit of the budget per period")
+		c.Flags().StringVar(&budgetLimitCurrency, "limit-currency", money.USD, "Currency the limit is given in, converted into dollars with the current exchange rate")
+		c.Flags().StringVar(&budgetPeriod, "period", "monthly", "Period the limit applies to, one of monthly, quarterly, annually")
+		c.Flags().StringSliceVar(&budgetTags, "tag", nil, "Only include costs with these tags, given as key=value")
+
+		addAccountFilterFlags(c)
+		addDimensionFilterFlags(c)
+	}
+	budgetCreateCmd.MarkFlagRequired("limit")
+	budgetUpdateCmd.Flags().BoolVar(&budgetClearFilters, "clear-filters", false, "Remove all filters, so the budget covers all costs")
+
+	for _, c := range []*cobra.Command{budgetCreateCmd, budgetUpdateCmd, budgetDeleteCmd} {
+		c.Flags().BoolVar(&budgetDryRun, "dry-run", false, "Only print what would change")
+		budgetCmd.AddCommand(c)
+	}
+}
+
+func budgetAccountId() (string, error) {
+	organizationResult, err := backend.Accounts.DescribeOrganization(&organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(organizationResult.Organization.MasterAccountId), nil
+}
+
+// findBudget returns the budget with the given name, nil if there is none.
+func findBudget(accountId string, name string) (*budgets.Budget, error) {
+	budgetList, err := billing.DescribeBudgets(backend.Budgets, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, budget := range budgetList {
+		if aws.StringValue(budget.BudgetName) == name {
+			return budget, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// loadLimitExchangeRate makes sure the rate of the currency the limit is
+// given in is loaded, even if amounts are not converted into it.
+func loadLimitExchangeRate(cmd *cobra.Command) error {
+	rateCurrencies := selectedCurrencies()
+
+	currency := strings.ToUpper(budgetLimitCurrency)
+	if currency != money.USD && !slices.Contains(rateCurrencies, currency) {
+		rateCurrencies = append(rateCurrencies, currency)
+	}
+
+	return loadExchangeRates(cmd, rateCurrencies)
+}
+
+// requireTableOutput refuses structured output formats for commands that only
+// state what they did.
+func requireTableOutput(cmd *cobra.Command) error {
+	if outputFormat != output.FormatTable {
+		return fmt.Errorf("%s does not support --output=%s", cmd.CommandPath(), outputFormat)
+	}
+
+	return nil
+}
+
+// budgetLimitSpend returns the limit given with --limit in dollars.
+func budgetLimitSpend() (*budgets.Spend, error) {
+	limit, err := money.ParseAmount(strings.TrimSpace(budgetLimit), strings.ToUpper(budgetLimitCurrency))
+	if err != nil {
+		return nil, fmt.Errorf("invalid limit %q: %w", budgetLimit, err)
+	}
+	if limit.Decimal().Sign() <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	dollar, err := money.ToDollar(limit, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &budgets.Spend{
+		Amount: aws.String(dollar.Decimal().StringFixed(2)),
+		Unit:   aws.String(money.USD),
+	}, nil
+}
+
+func budgetTimeUnit() (string, error) {
+	timeUnit, ok := budgetPeriods[strings.ToLower(budgetPeriod)]
+	if !ok {
+		return "", fmt.Errorf("unknown period %q, must be one of monthly, quarterly, annually", budgetPeriod)
+	}
+
+	return timeUnit, nil
+}
+
+func budgetFiltersChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{"account", "exclude-account", "ou", "service", "region", "tag"} {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// budgetCostFilters returns the cost filters for the account, organizational
+// unit, service, region and tag filters, nil if there are none.
+func budgetCostFilters() (map[string][]*string, error) {
+	filters := map[string][]*string{}
+	period := lastMonthToDate()
+
+	if accountsFiltered() {
+		accounts, err := billing.ListAccounts(backend.Accounts)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts, err = filterAccountList(accounts)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(accounts) == 0 {
+			return nil, fmt.Errorf("no accounts match the filters")
+		}
+
+		for _, account := range accounts {
+			filters["LinkedAccount"] = append(filters["LinkedAccount"], account.Id)
+		}
+	}
+
+	if len(filterServices) > 0 {
+		services, err := resolveDimension("SERVICE", filterServices, period)
+		if err != nil {
+			return nil, err
+		}
+		filters["Service"] = aws.StringSlice(services)
+	}
+
+	if len(filterRegions) > 0 {
+		regions, err := resolveDimension("REGION", filterRegions, period)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
+		filters["Region"] = aws.StringSlice(regions)
+	}
+
+	for _, tag := range budgetTags {
+		key, value, found := strings.Cut(tag, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid tag %q, must be key=value", tag)
+		}
+
+		// Budgets tell user defined tags from AWS generated ones by prefix.
+		if !strings.HasPrefix(key, "user:") && !strings.HasPrefix(key, "aws:") {
+			key = "user:" + key
+		}
+
+		filters["TagKeyValue"] = append(filters["TagKeyValue"], aws.String(key+"$"+value))
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	return filters, nil
+}
+
+func describeBudgetLimit(spend *budgets.Spend) (string, error) {
+	if spend == nil {
+		return MISSING, nil
+	}
+
+	dollar, err := money.BudgetLimitToDollar(&budgets.Budget{BudgetLimit: spend})
+	if err != nil {
+		return "", err
+	}
 
-		forecastEuro, err := money.BudgetForecastToEuro(budget)
+	converted := []string{}
+	for _, currency := range selectedCurrencies() {
+		amount, err := money.Convert(dollar, currency, nil)
 		if err != nil {
-			log.Fatal(err)
+			return "", err
 		}
+		converted = append(converted, ESTIMATED+money.Format(amount))
+	}
+
+	if len(converted) == 0 {
+		return money.Format(dollar), nil
+	}
 
-		forecastDeltaDollar := forecastDollar - limitDollar
-		forecastDeltaEuro := forecastEuro - limitEuro
+	return fmt.Sprintf("%s (%s)", money.Format(dollar), strings.Join(converted, ", ")), nil
+}
 
-		s := fmt.Sprintf(
-			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
-			*budget.BudgetName,
-			money.Float64DollarToStringDollar(limitDollar),
-			money.Float64EuroToStringEuro(limitEuro),
-			money.Float64DollarToStringDollar(spendDollar),
-			money.Float64EuroToStringEuro(spendEuro),
-			money.Float64DollarToStringDollar(forecastDollar),
-			money.Float64EuroToStringEuro(forecastEuro),
-			money.Float64DollarToStringDollar(forecastDeltaDollar),
-			money.Float64EuroToStringEuro(forecastDeltaEuro),
-		)
-		fmt.Fprintln(w, s)
+func describeBudgetFilter(filters map[string][]*string, key string) string {
+	values := aws.StringValueSlice(filters[key])
+	if len(values) == 0 {
+		return "(all)"
 	}
 
-	w.Flush()
+	return strings.Join(values, ", ")
+}
+
+// budgetChanges describes how a budget changes, old is nil for new budgets
+// and budget is nil for deleted ones.
+func budgetChanges(old *budgets.Budget, budget *budgets.Budget) ([]string, error) {
+	if old == nil {
+		old = &budgets.Budget{}
+	}
+	if budget == nil {
+		budget = &budgets.Budget{}
+	}
+
+	changes := []string{}
+	change := func(name, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", name, from, to))
+		}
+	}
+
+	oldLimit, err := describeBudgetLimit(old.BudgetLimit)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := describeBudgetLimit(budget.BudgetLimit)
+	if err != nil {
+		return nil, err
+	}
+	change("limit", oldLimit, limit)
+
+	timeUnit := func(b *budgets.Budget) string {
+		if b.TimeUnit == nil {
+			return MISSING
+		}
+		return strings.ToLower(aws.StringValue(b.TimeUnit))
+	}
+	change("period", timeUnit(old), timeUnit(budget))
+
+	for _, filter := range budgetFilterNames {
+		change(filter.Name, describeBudgetFilter(old.CostFilters, filter.Key), describeBudgetFilter(budget.CostFilters, filter.Key))
+	}
+
+	return changes, nil
+}
+
+// writeBudgetChanges states what was done to a budget, given as create,
+// update or delete, or with --dry-run what would be done.
+func writeBudgetChanges(cmd *cobra.Command, verb string, name string, changes []string) {
+	if budgetDryRun {
+		verb = "Would " + verb
+	} else {
+		verb = strings.ToUpper(verb[:1]) + verb[1:] + "d"
+	}
+
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%s budget %q\n", verb, name)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+}
+
+func runBudgetCreate(cmd *cobra.Command, args []string) error {
+	if err := requireTableOutput(cmd); err != nil {
+		return err
+	}
+	if err := loadLimitExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	name := args[0]
+
+	accountId, err := budgetAccountId()
+	if err != nil {
+		return err
+	}
+
+	existing, err := findBudget(accountId, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("budget %q already exists, use update to change it", name)
+	}
+
+	limit, err := budgetLimitSpend()
+	if err != nil {
+		return err
+	}
+
+	timeUnit, err := budgetTimeUnit()
+	if err != nil {
+		return err
+	}
+
+	filters, err := budgetCostFilters()
+	if err != nil {
+		return err
+	}
+
+	budget := &budgets.Budget{
+		BudgetName:  aws.String(name),
+		BudgetType:  aws.String(budgets.BudgetTypeCost),
+		BudgetLimit: limit,
+		TimeUnit:    aws.String(timeUnit),
+		CostFilters: filters,
+	}
+
+	if !budgetDryRun {
+		_, err := backend.Budgets.CreateBudget(&budgets.CreateBudgetInput{
+			AccountId: aws.String(accountId),
+			Budget:    budget,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, err := budgetChanges(nil, budget)
+	if err != nil {
+		return err
+	}
+
+	writeBudgetChanges(cmd, "create", name, changes)
+
+	return nil
+}
+
+func runBudgetUpdate(cmd *cobra.Command, args []string) error {
+	if err := requireTableOutput(cmd); err != nil {
+		return err
+	}
+	if err := loadLimitExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	name := args[0]
+
+	accountId, err := budgetAccountId()
+	if err != nil {
+		return err
+	}
+
+	old, err := findBudget(accountId, name)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return fmt.Errorf("no budget %q", name)
+	}
+	if budgetType := aws.StringValue(old.BudgetType); budgetType != "" && budgetType != budgets.BudgetTypeCost {
+		return fmt.Errorf("budget %q is a %s budget, only cost budgets can be updated", name, strings.ToLower(budgetType))
+	}
+
+	// Calculated spend and update times are maintained by AWS.
+	budget := &budgets.Budget{
+		BudgetName:          old.BudgetName,
+		BudgetType:          old.BudgetType,
+		BudgetLimit:         old.BudgetLimit,
+		TimeUnit:            old.TimeUnit,
+		TimePeriod:          old.TimePeriod,
+		CostFilters:         old.CostFilters,
+		CostTypes:           old.CostTypes,
+		AutoAdjustData:      old.AutoAdjustData,
+		PlannedBudgetLimits: old.PlannedBudgetLimits,
+	}
+
+	if cmd.Flags().Changed("limit-currency") && !cmd.Flags().Changed("limit") {
+		return fmt.Errorf("--limit-currency needs --limit")
+	}
+
+	// Planned and auto-adjusting budgets derive their limits from their own
+	// settings, which a single limit or another period would contradict.
+	if cmd.Flags().Changed("limit") || cmd.Flags().Changed("period") {
+		if len(old.PlannedBudgetLimits) > 0 {
+			return fmt.Errorf("budget %q has planned limits, --limit and --period cannot be changed from here", name)
+		}
+		if old.AutoAdjustData != nil {
+			return fmt.Errorf("budget %q adjusts its limit automatically, --limit and --period cannot be changed from here", name)
+		}
+	}
+
+	if cmd.Flags().Changed("limit") {
+		budget.BudgetLimit, err = budgetLimitSpend()
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.Flags().Changed("period") {
+		timeUnit, err := budgetTimeUnit()
+		if err != nil {
+			return err
+		}
+		budget.TimeUnit = aws.String(timeUnit)
+
+		// The old period starts at the old unit, AWS starts a new one at
+		// the beginning of the current one.
+		if timeUnit != aws.StringValue(old.TimeUnit) {
+			budget.TimePeriod = nil
+		}
+	}
+
+	if budgetClearFilters && budgetFiltersChanged(cmd) {
+		return fmt.Errorf("--clear-filters cannot be combined with filters")
+	}
+	if budgetClearFilters {
+		budget.CostFilters = nil
+	}
+	if budgetFiltersChanged(cmd) {
+		budget.CostFilters, err = budgetCostFilters()
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, err := budgetChanges(old, budget)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Budget %q is unchanged\n", name)
+		return nil
+	}
+
+	if !budgetDryRun {
+		_, err := backend.Budgets.UpdateBudget(&budgets.UpdateBudgetInput{
+			AccountId: aws.String(accountId),
+			NewBudget: budget,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writeBudgetChanges(cmd, "update", name, changes)
+
+	return nil
+}
+
+func runBudgetDelete(cmd *cobra.Command, args []string) error {
+	if err := requireTableOutput(cmd); err != nil {
+		return err
+	}
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	name := args[0]
+
+	accountId, err := budgetAccountId()
+	if err != nil {
+		return err
+	}
+
+	old, err := findBudget(accountId, name)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return fmt.Errorf("no budget %q", name)
+	}
+
+	if !budgetDryRun {
+		_, err := backend.Budgets.DeleteBudget(&budgets.DeleteBudgetInput{
+			AccountId:  aws.String(accountId),
+			BudgetName: aws.String(name),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, err := budgetChanges(old, nil)
+	if err != nil {
+		return err
+	}
+
+	writeBudgetChanges(cmd, "delete", name, changes)
+
+	return nil
 }
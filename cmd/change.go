@@ -3,265 +3,581 @@ package cmd
 import (
 	"cmp"
 	"fmt"
-	"log"
-	"os"
 	"slices"
 	"strings"
-	"text/tabwriter"
-	"time"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
-	"github.com/aws/aws-sdk-go/service/organizations"
-	"github.com/giantswarm/abu/money"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
 )
 
 var (
 	MONTH_LOOKBACK  = 3
 	MAX_CONCURRENCY = 5
+	MAX_REQUESTS    = 100
 	NUM_LINES       = 10
 )
 
 var changeCmd = &cobra.Command{
 	Use:   "change",
 	Short: "Print information on changes in costs",
-	Run:   runChange,
+	RunE:  runChange,
 }
 
+var (
+	changeBy          []string
+	changeSort        string
+	changeMinCost     float64
+	changeMinDelta    float64
+	changeTop         int
+	changeLookback    int
+	changeBaseline    string
+	changeMTD         bool
+	changeMaxRequests int
+)
+
+var (
+	changeSorts     = []string{"abs", "increase", "decrease", "percent"}
+	changeBaselines = []string{"previous", "average", "median"}
+)
+
 func init() {
+	changeCmd.Flags().StringVar(&changeSort, "sort", "abs", "Order of the changes, one of abs (largest either way), increase, decrease, percent")
+	changeCmd.Flags().Float64Var(&changeMinCost, "min-cost", 0, "Leave out costs below this many dollars both in the baseline and the compared month")
+	changeCmd.Flags().Float64Var(&changeMinDelta, "min-delta", 0, "Leave out changes smaller than this many dollars either way")
+	changeCmd.Flags().IntVar(&changeTop, "top", NUM_LINES, "Number of changes to show, 0 for all")
+	changeCmd.Flags().IntVar(&changeLookback, "lookback", MONTH_LOOKBACK, "Number of months before the compared month the baseline is taken from")
+	changeCmd.Flags().StringVar(&changeBaseline, "baseline", "average", "Cost the compared month is measured against, one of previous (month), average or median (of the lookback months)")
+	changeCmd.Flags().BoolVar(&changeMTD, "month-to-date", false, "Compare the current month to date, projected to the full month, instead of the last complete month")
+	changeCmd.Flags().StringSliceVar(&changeBy, "by", []string{"account", "service", "region"}, "Report changes by these dimensions, e.g. usage-type or instance-type, tags given as tag:<key> or cost categories given as costcategory:<name>")
+	changeCmd.Flags().IntVar(&changeMaxRequests, "max-requests", MAX_REQUESTS, "Maximum number of Cost Explorer requests more than two --by keys may be split into")
+
+	// --group-by is accepted like in bills, but changes can be reported by
+	// more than one key.
+	changeCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "group-by" {
+			name = "by"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	addMetricFlag(changeCmd)
+	addCostFilterFlags(changeCmd)
+
 	rootCmd.AddCommand(changeCmd)
 }
 
-func runChange(cmd *cobra.Command, args []string) {
-	now := aws.String(time.Now().AddDate(0, -MONTH_LOOKBACK, -time.Now().Day()+1).Format("2006-01-02"))
-	lookback := aws.String(time.Now().AddDate(0, 0, -time.Now().Day()+1).Format("2006-01-02"))
-
-	type Request struct {
-		AccountName *string
-		AccountId   *string
-		Service     *string
-		Region      *string
-		Start       *string
-		End         *string
+// parseGroupByList returns the group definitions selected with --by.
+func parseGroupByList(values []string) ([]*costexplorer.GroupDefinition, error) {
+	definitions := []*costexplorer.GroupDefinition{}
+	seen := map[string]bool{}
+
+	for _, by := range values {
+		definition, err := billing.ParseGroupBy(strings.TrimSpace(by))
+		if err != nil {
+			return nil, err
+		}
+
+		id := aws.StringValue(definition.Type) + ":" + aws.StringValue(definition.Key)
+		if seen[id] {
+			return nil, fmt.Errorf("%s given more than once in --by", by)
+		}
+		seen[id] = true
+
+		definitions = append(definitions, definition)
+	}
+
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("--by must not be empty")
 	}
 
-	type Result struct {
-		AccountName        *string
-		AccountId          *string
-		Service            *string
-		Region             *string
-		Start              *string
-		End                *string
-		CostAndUsageOutput *costexplorer.GetCostAndUsageOutput
+	return definitions, nil
+}
+
+// split is a part of the costs requested separately, selected by the filter
+// and keyed by the group keys Cost Explorer would have returned for it.
+type split struct {
+	Keys   []string
+	Filter *costexplorer.Expression
+}
+
+// splitFilters returns the splits for every combination of the values of
+// the groups, given as returned by groupFilters.
+func splitFilters(groups []map[string]*costexplorer.Expression) []split {
+	splits := []split{{}}
+
+	for _, filters := range groups {
+		combined := []split{}
+		for _, s := range splits {
+			for key, f := range filters {
+				combined = append(combined, split{
+					Keys:   append(append([]string{}, s.Keys...), key),
+					Filter: billing.And(s.Filter, f),
+				})
+			}
+		}
+		splits = combined
 	}
 
-	requests := []Request{}
+	return splits
+}
+
+// groupFilters returns a filter for each value a group definition takes in
+// the period, keyed by the group key Cost Explorer would return for it.
+func groupFilters(definition *costexplorer.GroupDefinition, filter *costexplorer.Expression, period *costexplorer.DateInterval) (map[string]*costexplorer.Expression, error) {
+	key := aws.StringValue(definition.Key)
+	filters := map[string]*costexplorer.Expression{}
+
+	if aws.StringValue(definition.Type) == costexplorer.GroupDefinitionTypeTag {
+		values, err := billing.GetTags(backend.Costs, &costexplorer.GetTagsInput{
+			TagKey:     definition.Key,
+			Filter:     filter,
+			TimePeriod: period,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range values {
+			filters[key+"$"+value] = &costexplorer.Expression{
+				Tags: &costexplorer.TagValues{
+					Key:    definition.Key,
+					Values: []*string{aws.String(value)},
+				},
+			}
+		}
+
+		filters[key+"$"] = &costexplorer.Expression{
+			Tags: &costexplorer.TagValues{
+				Key:          definition.Key,
+				MatchOptions: []*string{aws.String(costexplorer.MatchOptionAbsent)},
+			},
+		}
 
-	listAccountsResult, err := organizationsSvc.ListAccounts(&organizations.ListAccountsInput{})
+		return filters, nil
+	}
+
+	if aws.StringValue(definition.Type) == costexplorer.GroupDefinitionTypeCostCategory {
+		values, err := billing.GetCostCategories(backend.Costs, &costexplorer.GetCostCategoriesInput{
+			CostCategoryName: definition.Key,
+			Filter:           filter,
+			TimePeriod:       period,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range values {
+			filters[key+"$"+value] = &costexplorer.Expression{
+				CostCategories: &costexplorer.CostCategoryValues{
+					Key:    definition.Key,
+					Values: []*string{aws.String(value)},
+				},
+			}
+		}
+
+		filters[key+"$"] = &costexplorer.Expression{
+			CostCategories: &costexplorer.CostCategoryValues{
+				Key:          definition.Key,
+				MatchOptions: []*string{aws.String(costexplorer.MatchOptionAbsent)},
+			},
+		}
+
+		return filters, nil
+	}
+
+	dimensionValues, err := billing.GetDimensionValues(backend.Costs, &costexplorer.GetDimensionValuesInput{
+		Dimension:  definition.Key,
+		Filter:     filter,
+		TimePeriod: period,
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	for _, v := range dimensionValues {
+		filters[aws.StringValue(v.Value)] = dimensionExpression(key, []string{aws.StringValue(v.Value)})
 	}
 
-	describeRegionsResult, err := ec2Svc.DescribeRegions(nil)
+	return filters, nil
+}
+
+func runChange(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	metric, err := selectedMetric()
 	if err != nil {
-		fmt.Println("Error", err)
-		return
+		return err
 	}
-	regions := []*string{}
-	for _, region := range describeRegionsResult.Regions {
-		regions = append(regions, region.RegionName)
+
+	groupBy, err := parseGroupByList(changeBy)
+	if err != nil {
+		return err
 	}
 
-	for _, account := range listAccountsResult.Accounts {
-		getDimensionValuesResult, err := costExplorerSvc.GetDimensionValues(&costexplorer.GetDimensionValuesInput{
-			Dimension: aws.String("SERVICE"),
-			TimePeriod: &costexplorer.DateInterval{
-				Start: now,
-				End:   lookback,
-			},
-		})
-		if err != nil {
-			log.Fatal(err)
+	if !slices.Contains(changeSorts, changeSort) {
+		return fmt.Errorf("unknown sort %q, must be one of %s", changeSort, strings.Join(changeSorts, ", "))
+	}
+	if !slices.Contains(changeBaselines, changeBaseline) {
+		return fmt.Errorf("unknown baseline %q, must be one of %s", changeBaseline, strings.Join(changeBaselines, ", "))
+	}
+	if changeLookback < 1 {
+		return fmt.Errorf("--lookback must be at least 1")
+	}
+	if changeTop < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+
+	now := backend.Now()
+	firstOfMonth := now.AddDate(0, 0, -now.Day()+1)
+
+	// The compared month is the last complete one, or with --month-to-date
+	// the current one with its cost scaled up to the full month.
+	compared := firstOfMonth.AddDate(0, -1, 0)
+	end := firstOfMonth
+	elapsed := 0
+
+	if changeMTD {
+		elapsed = now.Day() - 1
+		if elapsed == 0 {
+			return fmt.Errorf("no complete day this month to compare yet, leave out --month-to-date")
 		}
 
-		for _, v := range getDimensionValuesResult.DimensionValues {
-			service := v.Value
-
-			for _, region := range regions {
-				requests = append(requests, Request{
-					AccountName: account.Name,
-					AccountId:   account.Id,
-					Service:     service,
-					Region:      region,
-					Start:       now,
-					End:         lookback,
-				})
+		compared = firstOfMonth
+		end = now
+	}
+
+	period := &costexplorer.DateInterval{
+		Start: aws.String(compared.AddDate(0, -changeLookback, 0).Format("2006-01-02")),
+		End:   aws.String(end.Format("2006-01-02")),
+	}
+
+	filter, err := costFilter(period)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := billing.ListAccounts(backend.Accounts)
+	if err != nil {
+		return err
+	}
+
+	accountNames := map[string]string{}
+	for _, account := range accounts {
+		accountNames[*account.Id] = *account.Name
+	}
+
+	// Cost Explorer allows at most two group by keys per request, so any
+	// further keys are handled by making one request per combination of the
+	// values they take. Those are the keys taking the fewest values, which
+	// keeps the number of requests down. Indexes are positions in groupBy.
+	requestIndexes := []int{}
+	splitIndexes := []int{}
+	splits := []split{{}}
+
+	if len(groupBy) > 2 {
+		filters := make([]map[string]*costexplorer.Expression, len(groupBy))
+		indexes := []int{}
+
+		for i, definition := range groupBy {
+			filters[i], err = groupFilters(definition, filter, period)
+			if err != nil {
+				return err
+			}
+			indexes = append(indexes, i)
+		}
+
+		slices.SortStableFunc(indexes, func(a, b int) int {
+			return cmp.Compare(len(filters[a]), len(filters[b]))
+		})
+
+		splitIndexes = indexes[:len(groupBy)-2]
+		requestIndexes = indexes[len(groupBy)-2:]
+		slices.Sort(splitIndexes)
+		slices.Sort(requestIndexes)
+
+		requests := 1
+		splitGroups := []map[string]*costexplorer.Expression{}
+		for _, i := range splitIndexes {
+			requests *= len(filters[i])
+			if requests > changeMaxRequests {
+				return fmt.Errorf("--by %s needs more than %d requests, leave out keys, narrow down the costs with filters or raise --max-requests", strings.Join(changeBy, ","), changeMaxRequests)
 			}
+			splitGroups = append(splitGroups, filters[i])
+		}
+
+		splits = splitFilters(splitGroups)
+	} else {
+		for i := range groupBy {
+			requestIndexes = append(requestIndexes, i)
 		}
 	}
 
+	requestGroupBy := []*costexplorer.GroupDefinition{}
+	for _, i := range requestIndexes {
+		requestGroupBy = append(requestGroupBy, groupBy[i])
+	}
+
+	type Key string
+
+	var mu sync.Mutex
+	groups := map[Key][]*costexplorer.Group{}
+	keys := map[Key][]string{}
+	periods := []*costexplorer.DateInterval{}
+
 	var g errgroup.Group
 	g.SetLimit(MAX_CONCURRENCY)
 
-	resultsChannel := make(chan Result, MAX_CONCURRENCY+1)
-
-	for _, request := range requests {
-		r := request
-
-		go g.Go(func() error {
-			getCostAndUsageInput := &costexplorer.GetCostAndUsageInput{
-				Filter: &costexplorer.Expression{
-					And: []*costexplorer.Expression{
-						{
-							Dimensions: &costexplorer.DimensionValues{
-								Key:    aws.String("LINKED_ACCOUNT"),
-								Values: []*string{r.AccountId},
-							},
-						},
-						{
-							Dimensions: &costexplorer.DimensionValues{
-								Key:    aws.String("SERVICE"),
-								Values: []*string{r.Service},
-							},
-						},
-						{
-							Dimensions: &costexplorer.DimensionValues{
-								Key:    aws.String("REGION"),
-								Values: []*string{r.Region},
-							},
-						},
-					},
-				},
-				Granularity: aws.String("MONTHLY"),
-				Metrics:     []*string{aws.String("UnblendedCost")},
-				TimePeriod: &costexplorer.DateInterval{
-					Start: r.Start,
-					End:   r.End,
-				},
-			}
+	for _, s := range splits {
+		s := s
 
-			getCostAndUsageOutput, err := costExplorerSvc.GetCostAndUsage(getCostAndUsageInput)
+		g.Go(func() error {
+			getCostAndUsageOutput, err := billing.GetCostAndUsage(backend.Costs, &costexplorer.GetCostAndUsageInput{
+				Filter:      billing.And(s.Filter, filter),
+				Granularity: aws.String("MONTHLY"),
+				GroupBy:     requestGroupBy,
+				Metrics:     []*string{aws.String(metric.Usage)},
+				TimePeriod:  period,
+			})
 			if err != nil {
 				return err
 			}
 
-			resultsChannel <- Result{
-				AccountName:        r.AccountName,
-				AccountId:          r.AccountId,
-				Service:            r.Service,
-				Region:             r.Region,
-				Start:              r.Start,
-				End:                r.End,
-				CostAndUsageOutput: getCostAndUsageOutput,
+			mu.Lock()
+			defer mu.Unlock()
+
+			resultsByTime := getCostAndUsageOutput.ResultsByTime
+			for i, resultByTime := range resultsByTime {
+				if i == len(periods) {
+					periods = append(periods, resultByTime.TimePeriod)
+				}
+
+				for _, group := range resultByTime.Groups {
+					groupKeys := make([]string, len(groupBy))
+					for j, key := range aws.StringValueSlice(group.Keys) {
+						groupKeys[requestIndexes[j]] = key
+					}
+					for j, key := range s.Keys {
+						groupKeys[splitIndexes[j]] = key
+					}
+
+					key := Key(strings.Join(groupKeys, "\x00"))
+
+					if _, ok := groups[key]; !ok {
+						groups[key] = make([]*costexplorer.Group, len(resultsByTime))
+						keys[key] = groupKeys
+					}
+					groups[key][i] = group
+				}
 			}
 
 			return nil
 		})
 	}
 
-	go func() {
-		g.Wait()
-		if err := g.Wait(); err != nil {
-			log.Fatal(err)
-		}
-		close(resultsChannel)
-	}()
-
-	results := []Result{}
-
-	for result := range resultsChannel {
-		results = append(results, result)
+	if err := g.Wait(); err != nil {
+		return err
 	}
 
+	// Percent is nil for costs that are new in the compared month.
 	type Line struct {
-		Name         string
-		Id           string
-		Service      string
-		Region       string
-		DollarCost   float64
-		EuroCost     float64
-		DollarChange float64
-		EuroChange   float64
+		Keys     []string
+		Cost     amounts
+		Baseline amounts
+		Change   amounts
+		Percent  *decimal.Decimal
 	}
 
-	lines := []Line{}
-	for _, result := range results {
-		r := result.CostAndUsageOutput.ResultsByTime
-		firstResult := r[0]
-		lastResult := r[len(r)-1]
+	minCost := decimal.NewFromFloat(changeMinCost)
+	minDelta := decimal.NewFromFloat(changeMinDelta)
 
-		firstDollarCost, err := money.CostExplorerResultByTimeToDollar(firstResult)
-		if err != nil {
-			log.Fatal(err)
+	lines := []Line{}
+	for key, g := range groups {
+		// Without a month to compare and one to compare it with there is
+		// no change to report.
+		if len(periods) < 2 {
+			break
 		}
-		firstEuroCost, err := money.CostExplorerResultByTimeToEuro(firstResult)
-		if err != nil {
-			log.Fatal(err)
+
+		costs := []amounts{}
+		for i, group := range g {
+			dollarCost, err := money.CostExplorerGroupToDollar(group, metric.Usage)
+			if err != nil {
+				return err
+			}
+
+			cost, err := convert(dollarCost, periods[i])
+			if err != nil {
+				return err
+			}
+			costs = append(costs, cost)
 		}
 
-		lastDollarCost, err := money.CostExplorerResultByTimeToDollar(lastResult)
+		cost := costs[len(costs)-1]
+		if changeMTD {
+			cost = cost.mul(decimal.New(int64(firstOfMonth.AddDate(0, 1, -1).Day()), 0)).div(int64(elapsed))
+		}
+		baseline, err := changeBaselineOf(costs[:len(costs)-1])
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		lastEuroCost, err := money.CostExplorerResultByTimeToEuro(lastResult)
+		delta, err := cost.sub(baseline)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		dollarChange := lastDollarCost - firstDollarCost
-		euroChange := lastEuroCost - firstEuroCost
-
 		line := Line{
-			Name:         *result.AccountName,
-			Id:           *result.AccountId,
-			Service:      *result.Service,
-			Region:       *result.Region,
-			DollarCost:   lastDollarCost,
-			EuroCost:     lastEuroCost,
-			DollarChange: dollarChange,
-			EuroChange:   euroChange,
+			Keys:     keys[key],
+			Cost:     cost,
+			Baseline: baseline,
+			Change:   delta,
+		}
+
+		change := line.Change[money.USD].Decimal()
+		baselineDollars := baseline[money.USD].Decimal()
+
+		if baselineDollars.LessThan(minCost) && cost[money.USD].Decimal().LessThan(minCost) {
+			continue
+		}
+		if change.Abs().LessThan(minDelta) {
+			continue
+		}
+		if (changeSort == "increase" && change.Sign() <= 0) || (changeSort == "decrease" && change.Sign() >= 0) {
+			continue
+		}
+
+		if !baselineDollars.IsZero() {
+			percent := change.Div(baselineDollars).Mul(decimal.New(100, 0))
+			line.Percent = &percent
+		} else if change.IsZero() {
+			line.Percent = &decimal.Zero
 		}
 
 		lines = append(lines, line)
 	}
 
 	slices.SortFunc(lines, func(a, b Line) int {
-		return cmp.Compare(b.DollarChange, a.DollarChange)
+		aChange, bChange := a.Change[money.USD].Decimal(), b.Change[money.USD].Decimal()
+
+		switch changeSort {
+		case "increase":
+			return bChange.Cmp(aChange)
+		case "decrease":
+			return aChange.Cmp(bChange)
+		case "percent":
+			// New costs first, as their growth has no bound.
+			switch {
+			case a.Percent == nil && b.Percent == nil:
+				return bChange.Abs().Cmp(aChange.Abs())
+			case a.Percent == nil:
+				return -1
+			case b.Percent == nil:
+				return 1
+			default:
+				return b.Percent.Abs().Cmp(a.Percent.Abs())
+			}
+		default:
+			return bChange.Abs().Cmp(aChange.Abs())
+		}
 	})
 
-	if len(lines) > NUM_LINES {
-		lines = lines[:NUM_LINES]
+	if changeTop > 0 && len(lines) > changeTop {
+		lines = lines[:changeTop]
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	columns := []output.Column{}
+	for _, definition := range groupBy {
+		if isAccountGroup(definition) {
+			columns = append(columns, NAME_COLUMN, ID_COLUMN)
+		} else {
+			columns = append(columns, groupColumn(definition))
+		}
+	}
+	if changeMTD {
+		columns = append(columns, PROJECTED_COLUMNS.Columns()...)
+	} else {
+		columns = append(columns, COST_COLUMNS.Columns()...)
+	}
+	columns = append(columns, BASELINE_COLUMNS.Columns()...)
+	columns = append(columns, DELTA_COLUMNS.Columns()...)
+	columns = append(columns, PERCENT_COLUMN)
 
-	fmt.Fprintln(w, strings.Join([]string{
-		NAME_TITLE,
-		ID_TITLE,
-		SERVICE_TITLE,
-		REGION_TITLE,
-		COST_DOLLAR_TITLE,
-		COST_ESTIMATED_EURO_TITLE,
-		DELTA_DOLLAR_TITLE,
-		DELTA_ESTIMATED_EURO_TITLE,
-	}, "\t"))
+	t := output.NewTable(columns...)
 
 	for _, line := range lines {
-		s := fmt.Sprintf(
-			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t",
-			line.Name,
-			line.Id,
-			line.Service,
-			line.Region,
-			money.Float64DollarToStringDollar(line.DollarCost),
-			money.Float64EuroToStringEuro(line.EuroCost),
-			money.Float64DollarToStringDollar(line.DollarChange),
-			money.Float64EuroToStringEuro(line.EuroChange),
-		)
-		fmt.Fprintln(w, s)
+		cells := []output.Cell{}
+		for i, definition := range groupBy {
+			if isAccountGroup(definition) {
+				cells = append(cells, textCell(accountNames[line.Keys[i]]), textCell(line.Keys[i]))
+			} else {
+				cells = append(cells, groupCell(definition, line.Keys[i]))
+			}
+		}
+		cells = append(cells, moneyCells(line.Cost)...)
+		cells = append(cells, moneyCells(line.Baseline)...)
+		cells = append(cells, moneyCells(line.Change)...)
+		if line.Percent != nil {
+			cells = append(cells, output.Cell{Text: money.FormatPercent(*line.Percent), Value: output.Number(line.Percent.Round(2).String())})
+		} else {
+			cells = append(cells, output.Cell{Text: NEW})
+		}
+
+		t.AddRow(cells...)
 	}
 
-	w.Flush()
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+	writeExchangeRate(cmd)
+
+	return nil
+}
+
+// changeBaselineOf returns the cost the compared month is measured against,
+// given the costs of the months before it.
+func changeBaselineOf(costs []amounts) (amounts, error) {
+	switch changeBaseline {
+	case "average":
+		sum := amounts{}
+		for _, cost := range costs {
+			var err error
+			sum, err = sum.add(cost)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return sum.div(int64(len(costs))), nil
+
+	case "median":
+		// The median month is chosen on dollars, so the other currencies
+		// show the same month's amounts rather than medians of their own.
+		months := slices.Clone(costs)
+		slices.SortStableFunc(months, func(a, b amounts) int {
+			return a.get(money.USD).Decimal().Cmp(b.get(money.USD).Decimal())
+		})
+
+		if len(months)%2 == 0 {
+			sum, err := months[len(months)/2-1].add(months[len(months)/2])
+			if err != nil {
+				return nil, err
+			}
+			return sum.div(2), nil
+		}
+
+		return months[len(months)/2], nil
+
+	default:
+		return costs[len(costs)-1], nil
+	}
 }
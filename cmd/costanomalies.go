@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
+)
+
+var costAnomaliesCmd = &cobra.Command{
+	Use:   "cost-anomalies",
+	Short: "Print the anomalies found by AWS Cost Anomaly Detection",
+	RunE:  runCostAnomalies,
+}
+
+var costAnomaliesFeedbackCmd = &cobra.Command{
+	Use:   "feedback <anomaly-id> <yes|no|planned-activity>",
+	Short: "Tell AWS Cost Anomaly Detection whether an anomaly was one",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runCostAnomaliesFeedback,
+}
+
+var (
+	costAnomaliesFrom      string
+	costAnomaliesTo        string
+	costAnomaliesDays      int
+	costAnomaliesMonitors  []string
+	costAnomaliesFeedback  string
+	costAnomaliesMinImpact float64
+)
+
+func init() {
+	costAnomaliesCmd.Flags().StringVar(&costAnomaliesFrom, "from", "", "Start date (YYYY-MM-DD, inclusive), overrides --days")
+	costAnomaliesCmd.Flags().StringVar(&costAnomaliesTo, "to", "", "End date (YYYY-MM-DD, inclusive), defaults to today")
+	costAnomaliesCmd.Flags().IntVar(&costAnomaliesDays, "days", 30, "Number of days to show the anomalies of")
+	costAnomaliesCmd.Flags().StringSliceVar(&costAnomaliesMonitors, "monitor", nil, "Only include anomalies of monitors with these names or ARNs, globs allowed")
+	costAnomaliesCmd.Flags().StringVar(&costAnomaliesFeedback, "feedback", "", "Only include anomalies with this feedback, one of yes, no, planned-activity")
+	costAnomaliesCmd.Flags().Float64Var(&costAnomaliesMinImpact, "min-impact", 0, "Only include anomalies costing at least this many dollars more than expected")
+
+	costAnomaliesCmd.AddCommand(costAnomaliesFeedbackCmd)
+	rootCmd.AddCommand(costAnomaliesCmd)
+}
+
+// parseAnomalyFeedback returns the feedback type for a name like
+// planned-activity.
+func parseAnomalyFeedback(s string) (string, error) {
+	feedback := strings.ToUpper(strings.ReplaceAll(s, "-", "_"))
+
+	if !slices.Contains(costexplorer.AnomalyFeedbackType_Values(), feedback) {
+		return "", fmt.Errorf("unknown feedback %q, must be one of yes, no, planned-activity", s)
+	}
+
+	return feedback, nil
+}
+
+func feedbackCell(feedback *string) output.Cell {
+	if feedback == nil {
+		return output.Cell{Text: NO_FEEDBACK}
+	}
+
+	return output.Cell{
+		Text:  strings.ToLower(strings.ReplaceAll(*feedback, "_", " ")),
+		Value: *feedback,
+	}
+}
+
+// anomalyDate returns the day of a date Cost Anomaly Detection returns, which
+// may come with a time.
+func anomalyDate(date *string) string {
+	day, _, _ := strings.Cut(aws.StringValue(date), "T")
+
+	return day
+}
+
+// distinct returns the values in order of appearance, without duplicates and
+// empty values.
+func distinct(values []string) []string {
+	result := []string{}
+
+	for _, v := range values {
+		if v != "" && !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func costAnomaliesInterval(now time.Time) (*costexplorer.AnomalyDateInterval, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if costAnomaliesDays < 1 {
+		return nil, fmt.Errorf("--days must be at least 1")
+	}
+
+	from := today.AddDate(0, 0, -costAnomaliesDays+1).Format("2006-01-02")
+	to := today.Format("2006-01-02")
+
+	for flag, date := range map[string]*string{"from": &costAnomaliesFrom, "to": &costAnomaliesTo} {
+		if *date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", *date); err != nil {
+			return nil, fmt.Errorf("invalid --%s %q, must be YYYY-MM-DD", flag, *date)
+		}
+	}
+
+	if costAnomaliesFrom != "" {
+		from = costAnomaliesFrom
+	}
+	if costAnomaliesTo != "" {
+		to = costAnomaliesTo
+	}
+
+	if from > to {
+		return nil, fmt.Errorf("start date %s is after end date %s", from, to)
+	}
+
+	return &costexplorer.AnomalyDateInterval{
+		StartDate: aws.String(from),
+		EndDate:   aws.String(to),
+	}, nil
+}
+
+func runCostAnomalies(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	interval, err := costAnomaliesInterval(backend.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	input := &costexplorer.GetAnomaliesInput{
+		DateInterval: interval,
+	}
+
+	if costAnomaliesFeedback != "" {
+		feedback, err := parseAnomalyFeedback(costAnomaliesFeedback)
+		if err != nil {
+			return err
+		}
+		input.Feedback = aws.String(feedback)
+	}
+
+	if costAnomaliesMinImpact > 0 {
+		input.TotalImpact = &costexplorer.TotalImpactFilter{
+			NumericOperator: aws.String(costexplorer.NumericOperatorGreaterThanOrEqual),
+			StartValue:      aws.Float64(costAnomaliesMinImpact),
+		}
+	}
+
+	monitors, err := billing.GetAnomalyMonitors(backend.Anomalies)
+	if err != nil {
+		return err
+	}
+
+	monitorNames := map[string]string{}
+	for _, monitor := range monitors {
+		monitorNames[aws.StringValue(monitor.MonitorArn)] = aws.StringValue(monitor.MonitorName)
+	}
+
+	anomalies, err := billing.GetAnomalies(backend.Anomalies, input)
+	if err != nil {
+		return err
+	}
+
+	if len(costAnomaliesMonitors) > 0 {
+		anomalies = slices.DeleteFunc(anomalies, func(anomaly *costexplorer.Anomaly) bool {
+			arn := aws.StringValue(anomaly.MonitorArn)
+			return !matchesAny(costAnomaliesMonitors, monitorNames[arn], arn)
+		})
+	}
+
+	// Latest first, and the most costly first on the same day.
+	slices.SortFunc(anomalies, func(a, b *costexplorer.Anomaly) int {
+		if c := strings.Compare(anomalyDate(b.AnomalyStartDate), anomalyDate(a.AnomalyStartDate)); c != 0 {
+			return c
+		}
+		return money.AnomalyImpactToDollar(b).Decimal().Cmp(money.AnomalyImpactToDollar(a).Decimal())
+	})
+
+	columns := []output.Column{ID_COLUMN, START_COLUMN, END_COLUMN, MONITOR_COLUMN, SERVICE_COLUMN, ACCOUNT_COLUMN, REGION_COLUMN}
+	columns = append(columns, IMPACT_COLUMNS.Columns()...)
+	columns = append(columns, IMPACT_PERCENT_COLUMN, FEEDBACK_COLUMN)
+
+	t := output.NewTable(columns...)
+
+	for _, anomaly := range anomalies {
+		start := anomalyDate(anomaly.AnomalyStartDate)
+		end := anomalyDate(anomaly.AnomalyEndDate)
+
+		endCell := textCell(end)
+		if end == "" {
+			endCell = output.Cell{Text: ONGOING}
+		}
+
+		services, accounts, regions := []string{}, []string{}, []string{}
+		for _, rootCause := range anomaly.RootCauses {
+			services = append(services, aws.StringValue(rootCause.Service))
+			regions = append(regions, aws.StringValue(rootCause.Region))
+
+			account := aws.StringValue(rootCause.LinkedAccount)
+			if name := aws.StringValue(rootCause.LinkedAccountName); name != "" {
+				account = fmt.Sprintf("%s (%s)", name, account)
+			}
+			accounts = append(accounts, account)
+		}
+
+		// The rate of the day the anomaly started is used for it as a whole.
+		var period *costexplorer.DateInterval
+		if day, err := time.Parse("2006-01-02", start); err == nil {
+			period = &costexplorer.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(day.AddDate(0, 0, 1).Format("2006-01-02")),
+			}
+		}
+
+		monitor := monitorNames[aws.StringValue(anomaly.MonitorArn)]
+		if monitor == "" {
+			monitor = aws.StringValue(anomaly.MonitorArn)
+		}
+
+		cells := []output.Cell{
+			textCell(aws.StringValue(anomaly.AnomalyId)),
+			textCell(start),
+			endCell,
+			textCell(monitor),
+			{Text: strings.Join(distinct(services), ", "), Value: distinct(services)},
+			{Text: strings.Join(distinct(accounts), ", "), Value: distinct(accounts)},
+			{Text: strings.Join(distinct(regions), ", "), Value: distinct(regions)},
+		}
+		converted, err := convert(money.AnomalyImpactToDollar(anomaly), period)
+		if err != nil {
+			return err
+		}
+		cells = append(cells, moneyCells(converted)...)
+
+		if anomaly.Impact != nil && anomaly.Impact.TotalImpactPercentage != nil {
+			percent := decimal.NewFromFloat(*anomaly.Impact.TotalImpactPercentage)
+			cells = append(cells, output.Cell{Text: money.FormatPercent(percent), Value: output.Number(percent.Round(2).String())})
+		} else {
+			cells = append(cells, output.Cell{Text: MISSING})
+		}
+
+		cells = append(cells, feedbackCell(anomaly.Feedback))
+
+		t.AddRow(cells...)
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+	writeExchangeRate(cmd)
+
+	return nil
+}
+
+func runCostAnomaliesFeedback(cmd *cobra.Command, args []string) error {
+	feedback, err := parseAnomalyFeedback(args[1])
+	if err != nil {
+		return err
+	}
+
+	_, err = backend.Anomalies.ProvideAnomalyFeedback(&costexplorer.ProvideAnomalyFeedbackInput{
+		AnomalyId: aws.String(args[0]),
+		Feedback:  aws.String(feedback),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Gave feedback %s on anomaly %s\n", strings.ToLower(strings.ReplaceAll(feedback, "_", " ")), args[0])
+
+	return nil
+}
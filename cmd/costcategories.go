@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/output"
+)
+
+var costCategoriesCmd = &cobra.Command{
+	Use:   "costcategories [name]",
+	Short: "Print cost category definitions, or the costs by value of a cost category",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runCostCategories,
+}
+
+func init() {
+	addMetricFlag(costCategoriesCmd)
+	addCostFilterFlags(costCategoriesCmd)
+
+	rootCmd.AddCommand(costCategoriesCmd)
+}
+
+func runCostCategories(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		period := lastMonthToDate()
+
+		filter, err := costFilter(period)
+		if err != nil {
+			return err
+		}
+
+		return writeGroupCosts(cmd, &costexplorer.GroupDefinition{
+			Type: aws.String(costexplorer.GroupDefinitionTypeCostCategory),
+			Key:  aws.String(args[0]),
+		}, filter, period)
+	}
+
+	references, err := billing.ListCostCategoryDefinitions(backend.Costs)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(references, func(i, j int) bool {
+		return aws.StringValue(references[i].Name) < aws.StringValue(references[j].Name)
+	})
+
+	t := output.NewTable(NAME_COLUMN, RULES_COLUMN, DEFAULT_COLUMN, EFFECTIVE_COLUMN, VALUES_COLUMN)
+
+	for _, reference := range references {
+		rules := aws.Int64Value(reference.NumberOfRules)
+		values := aws.StringValueSlice(reference.Values)
+
+		// Effective dates are timestamps, the day is enough to read.
+		effective, _, _ := strings.Cut(aws.StringValue(reference.EffectiveStart), "T")
+
+		t.AddRow(
+			textCell(aws.StringValue(reference.Name)),
+			output.Cell{Text: strconv.FormatInt(rules, 10), Value: rules},
+			textCell(aws.StringValue(reference.DefaultValue)),
+			output.Cell{Text: effective, Value: aws.StringValue(reference.EffectiveStart)},
+			output.Cell{Text: strings.Join(values, ","), Value: values},
+		)
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+
+	return nil
+}
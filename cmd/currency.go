@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/abu/money"
+)
+
+var (
+	currencies []string
+)
+
+func init() {
+	defaultCurrencies := []string{money.EUR}
+	if s, ok := os.LookupEnv("ABU_CURRENCY"); ok {
+		defaultCurrencies = strings.Split(s, ",")
+	}
+
+	rootCmd.PersistentFlags().StringSliceVar(&currencies, "currency", defaultCurrencies, "Currencies to convert dollar amounts into, empty for none, defaults to $ABU_CURRENCY")
+}
+
+// selectedCurrencies returns the ISO 4217 codes of the currencies dollar
+// amounts are converted into.
+func selectedCurrencies() []string {
+	selected := []string{}
+
+	for _, currency := range currencies {
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if currency == "" || currency == money.USD || slices.Contains(selected, currency) {
+			continue
+		}
+		selected = append(selected, currency)
+	}
+
+	return selected
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
+)
+
+var (
+	EXCHANGE_RATE_SOURCES = []string{"api", "ecb", "static"}
+)
+
+var (
+	exchangeRateSource string
+	exchangeRates      []string
+	ecbFile            string
+	offline            bool
+	rateMethod         string
+)
+
+func init() {
+	defaultExchangeRates := []string{}
+	if s := os.Getenv("ABU_EXCHANGE_RATE"); s != "" {
+		defaultExchangeRates = strings.Split(s, ",")
+	}
+
+	defaultExchangeRateSource := "api"
+	if len(defaultExchangeRates) > 0 {
+		defaultExchangeRateSource = "static"
+	}
+
+	rootCmd.PersistentFlags().StringVar(&exchangeRateSource, "exchange-rate-source", defaultExchangeRateSource, "Source of the exchange rates, one of "+strings.Join(EXCHANGE_RATE_SOURCES, ", "))
+	rootCmd.PersistentFlags().StringSliceVar(&exchangeRates, "exchange-rate", defaultExchangeRates, "Static exchange rates as CURRENCY=RATE, or just RATE for the first currency, defaults to $ABU_EXCHANGE_RATE")
+	rootCmd.PersistentFlags().StringVar(&ecbFile, "ecb-file", os.Getenv("ABU_ECB_FILE"), "ECB reference rate XML file, defaults to $ABU_ECB_FILE")
+	rootCmd.PersistentFlags().StringVar(&rateMethod, "rate-method", money.RateMethodAverage, "Rate used for past periods when the exchange rate source has historical rates, one of "+strings.Join(money.RateMethods, ", "))
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Never access the network for exchange rates, use the cached rate instead")
+}
+
+func exchangeRateProvider(cmd *cobra.Command) (money.ExchangeRateProvider, error) {
+	if cmd.Flags().Changed("exchange-rate") && !cmd.Flags().Changed("exchange-rate-source") {
+		exchangeRateSource = "static"
+	}
+
+	switch exchangeRateSource {
+	case "api":
+		return money.NewCachedProvider(money.NewHTTPProvider(), offline), nil
+	case "ecb":
+		if ecbFile == "" {
+			return nil, fmt.Errorf("--ecb-file is required for exchange rate source ecb")
+		}
+		return &money.ECBFileProvider{Path: ecbFile}, nil
+	case "static":
+		values, err := staticExchangeRates()
+		if err != nil {
+			return nil, err
+		}
+		return &money.StaticProvider{Values: values, Source: "static"}, nil
+	default:
+		return nil, fmt.Errorf("unknown exchange rate source %q, must be one of %s", exchangeRateSource, strings.Join(EXCHANGE_RATE_SOURCES, ", "))
+	}
+}
+
+func staticExchangeRates() (map[string]decimal.Decimal, error) {
+	values := map[string]decimal.Decimal{}
+
+	for _, s := range exchangeRates {
+		currency, value, found := strings.Cut(s, "=")
+		if !found {
+			selected := selectedCurrencies()
+			if len(selected) == 0 {
+				return nil, fmt.Errorf("exchange rate %q without currency, but no currency selected", s)
+			}
+			currency, value = selected[0], s
+		}
+
+		f, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange rate %q: %w", s, err)
+		}
+
+		values[strings.ToUpper(strings.TrimSpace(currency))] = f
+	}
+
+	return values, nil
+}
+
+// loadExchangeRate sets the rates used by the money package for all selected
+// currencies, falling back to the built-in rates if no rate can be retrieved.
+func loadExchangeRate(cmd *cobra.Command) error {
+	return loadExchangeRates(cmd, selectedCurrencies())
+}
+
+// loadExchangeRates sets the rates of the given currencies, which need not be
+// selected for output.
+func loadExchangeRates(cmd *cobra.Command, currencies []string) error {
+	if len(currencies) == 0 {
+		return nil
+	}
+
+	provider, err := exchangeRateProvider(cmd)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(money.RateMethods, rateMethod) {
+		return fmt.Errorf("unknown rate method %q, must be one of %s", rateMethod, strings.Join(money.RateMethods, ", "))
+	}
+
+	for _, currency := range currencies {
+		rate, err := provider.Rate(currency)
+		if err != nil {
+			fallback, ok := money.FallbackRates[currency]
+			if !ok {
+				return fmt.Errorf("could not get %s exchange rate: %w", currency, err)
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Could not get %s exchange rate, using fallback: %v\n", currency, err)
+			rate = fallback
+		}
+
+		money.SetRate(rate)
+
+		if historical, ok := provider.(money.HistoricalExchangeRateProvider); ok {
+			rates, err := historical.Rates(currency)
+			if err != nil {
+				return err
+			}
+
+			money.SetHistoricalRates(currency, rates, rateMethod)
+		}
+	}
+
+	return nil
+}
+
+// writeExchangeRate states the rate a report used. It goes to stderr for
+// structured output formats so it does not end up in parsed output.
+func writeExchangeRate(cmd *cobra.Command) {
+	if len(selectedCurrencies()) == 0 {
+		return
+	}
+
+	w := cmd.OutOrStdout()
+	if outputFormat != output.FormatTable {
+		w = cmd.ErrOrStderr()
+	} else {
+		fmt.Fprintln(w)
+	}
+
+	for _, currency := range selectedCurrencies() {
+		rate, _ := money.CurrentRate(currency)
+		fmt.Fprintf(w, "Exchange rate: %s\n", rate)
+
+		if rates, method := money.HistoricalRates(currency); len(rates) > 0 {
+			fmt.Fprintf(w, "Past periods converted to %s with the %s rate of each period from %s\n", currency, method, rates[0].Source)
+
+			if periods := money.CurrentRatePeriods(currency); len(periods) > 0 {
+				starts := []string{}
+				for _, period := range periods {
+					starts = append(starts, aws.StringValue(period.Start))
+				}
+				fmt.Fprintf(w, "No %s rate before %s, periods starting %s converted with the current rate\n", currency, rates[0].Date.Format("2006-01-02"), strings.Join(starts, ", "))
+			}
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+)
+
+var (
+	filterAccounts        []string
+	filterExcludeAccounts []string
+	filterOUs             []string
+	filterServices        []string
+	filterRegions         []string
+	filterExpression      string
+)
+
+// addAccountFilterFlags adds the flags selecting accounts, see
+// filterAccountList.
+func addAccountFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&filterAccounts, "account", nil, "Only include accounts with these names or IDs, globs allowed")
+	cmd.Flags().StringSliceVar(&filterExcludeAccounts, "exclude-account", nil, "Exclude accounts with these names or IDs, globs allowed")
+	cmd.Flags().StringSliceVar(&filterOUs, "ou", nil, "Only include accounts in organizational units with these names or IDs, globs allowed")
+}
+
+// addDimensionFilterFlags adds the flags selecting services and regions.
+func addDimensionFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&filterServices, "service", nil, "Only include costs of these services, globs allowed")
+	cmd.Flags().StringSliceVar(&filterRegions, "region", nil, "Only include costs in these regions, globs allowed")
+}
+
+// addCostFilterFlags adds all flags costFilter takes into account.
+func addCostFilterFlags(cmd *cobra.Command) {
+	addAccountFilterFlags(cmd)
+	addDimensionFilterFlags(cmd)
+	cmd.Flags().StringVar(&filterExpression, "filter", "", `Only include costs matching the expression, e.g. 'service is "Amazon Simple Storage Service" and region in eu-west-1,eu-central-1 and not tag:team=platform'`)
+}
+
+// matchesAny returns whether one of the values matches one of the patterns,
+// ignoring case.
+func matchesAny(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value)); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func accountsFiltered() bool {
+	return len(filterAccounts) > 0 || len(filterExcludeAccounts) > 0 || len(filterOUs) > 0
+}
+
+// filterAccountList returns the accounts selected by --account, --ou and
+// --exclude-account.
+func filterAccountList(accounts []*organizations.Account) ([]*organizations.Account, error) {
+	if !accountsFiltered() {
+		return accounts, nil
+	}
+
+	for _, patterns := range [][]string{filterAccounts, filterExcludeAccounts, filterOUs} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	inOU := map[string]bool{}
+	if len(filterOUs) > 0 {
+		root, err := billing.DescribeOrganizationTree(backend.Accounts)
+		if err != nil {
+			return nil, err
+		}
+
+		root.Walk(func(ou *billing.OrganizationalUnit) {
+			if !matchesAny(filterOUs, ou.Name, ou.Id) {
+				return
+			}
+			for _, id := range ou.AccountIds() {
+				inOU[id] = true
+			}
+		})
+	}
+
+	filtered := []*organizations.Account{}
+
+	for _, account := range accounts {
+		name, id := aws.StringValue(account.Name), aws.StringValue(account.Id)
+
+		included := len(filterAccounts) == 0 && len(filterOUs) == 0
+		included = included || matchesAny(filterAccounts, name, id) || inOU[id]
+
+		if included && !matchesAny(filterExcludeAccounts, name, id) {
+			filtered = append(filtered, account)
+		}
+	}
+
+	return filtered, nil
+}
+
+// resolveDimension returns the values of a dimension matching the patterns in
+// the given period. Patterns without globs are taken as they are.
+func resolveDimension(dimension string, patterns []string, period *costexplorer.DateInterval) ([]string, error) {
+	values := []string{}
+	globs := []string{}
+
+	for _, pattern := range patterns {
+		if isGlob(pattern) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			globs = append(globs, pattern)
+		} else {
+			values = append(values, pattern)
+		}
+	}
+
+	if len(globs) == 0 {
+		return values, nil
+	}
+
+	dimensionValues, err := billing.GetDimensionValues(backend.Costs, &costexplorer.GetDimensionValuesInput{
+		Dimension:  aws.String(dimension),
+		TimePeriod: period,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range dimensionValues {
+		if matchesAny(globs, aws.StringValue(v.Value)) {
+			values = append(values, aws.StringValue(v.Value))
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no %s matches %s", strings.ToLower(dimension), strings.Join(patterns, ", "))
+	}
+
+	return values, nil
+}
+
+func dimensionExpression(dimension string, values []string) *costexplorer.Expression {
+	return &costexplorer.Expression{
+		Dimensions: &costexplorer.DimensionValues{
+			Key:    aws.String(dimension),
+			Values: aws.StringSlice(values),
+		},
+	}
+}
+
+// costFilter returns the Cost Explorer expression selecting the costs the
+// account, organizational unit, service and region filters ask for, nil if
+// there are no filters. Globs are resolved against the values seen in the
+// given period.
+func costFilter(period *costexplorer.DateInterval) (*costexplorer.Expression, error) {
+	expressions := []*costexplorer.Expression{}
+
+	if accountsFiltered() {
+		accounts, err := billing.ListAccounts(backend.Accounts)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts, err = filterAccountList(accounts)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(accounts) == 0 {
+			return nil, fmt.Errorf("no accounts match the filters")
+		}
+
+		ids := []string{}
+		for _, account := range accounts {
+			ids = append(ids, aws.StringValue(account.Id))
+		}
+
+		expressions = append(expressions, dimensionExpression("LINKED_ACCOUNT", ids))
+	}
+
+	if len(filterServices) > 0 {
+		services, err := resolveDimension("SERVICE", filterServices, period)
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, dimensionExpression("SERVICE", services))
+	}
+
+	if len(filterRegions) > 0 {
+		regions, err := resolveDimension("REGION", filterRegions, period)
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, dimensionExpression("REGION", regions))
+	}
+
+	if filterExpression != "" {
+		e, err := billing.ParseExpression(filterExpression)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := resolveExpression(e, period); err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, e)
+	}
+
+	return billing.And(expressions...), nil
+}
+
+// resolveExpression resolves account names and globs in the dimensions of a
+// parsed filter expression, as Cost Explorer only matches exact values.
+func resolveExpression(e *costexplorer.Expression, period *costexplorer.DateInterval) error {
+	for _, child := range append(append([]*costexplorer.Expression{}, e.And...), e.Or...) {
+		if err := resolveExpression(child, period); err != nil {
+			return err
+		}
+	}
+
+	if e.Not != nil {
+		return resolveExpression(e.Not, period)
+	}
+
+	if e.Dimensions == nil {
+		return nil
+	}
+
+	key := aws.StringValue(e.Dimensions.Key)
+	patterns := aws.StringValueSlice(e.Dimensions.Values)
+
+	if key != "LINKED_ACCOUNT" {
+		values, err := resolveDimension(key, patterns, period)
+		if err != nil {
+			return err
+		}
+
+		e.Dimensions.Values = aws.StringSlice(values)
+
+		return nil
+	}
+
+	accounts, err := billing.ListAccounts(backend.Accounts)
+	if err != nil {
+		return err
+	}
+
+	ids := []string{}
+	for _, account := range accounts {
+		if matchesAny(patterns, aws.StringValue(account.Name), aws.StringValue(account.Id)) {
+			ids = append(ids, aws.StringValue(account.Id))
+		}
+	}
+
+	if len(ids) == 0 {
+		return fmt.Errorf("no accounts match %s", strings.Join(patterns, ", "))
+	}
+
+	e.Dimensions.Values = aws.StringSlice(ids)
+
+	return nil
+}
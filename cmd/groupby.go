@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/output"
+)
+
+// isAccountGroup returns whether the definition groups by account, which is
+// shown as name and id.
+func isAccountGroup(definition *costexplorer.GroupDefinition) bool {
+	return aws.StringValue(definition.Type) == costexplorer.GroupDefinitionTypeDimension &&
+		aws.StringValue(definition.Key) == costexplorer.DimensionLinkedAccount
+}
+
+// groupColumn returns the column for the values of a group definition.
+func groupColumn(definition *costexplorer.GroupDefinition) output.Column {
+	key := aws.StringValue(definition.Key)
+
+	switch aws.StringValue(definition.Type) {
+	case costexplorer.GroupDefinitionTypeTag:
+		return output.Column{Key: "tag_" + key, Title: strings.ToUpper(key)}
+	case costexplorer.GroupDefinitionTypeCostCategory:
+		return output.Column{Key: "costcategory_" + key, Title: strings.ToUpper(key)}
+	}
+
+	return output.Column{Key: strings.ToLower(key), Title: strings.ReplaceAll(key, "_", " ")}
+}
+
+// groupCell returns the cell for a group key, costs without the tag or cost
+// category are shown as untagged or uncategorized.
+func groupCell(definition *costexplorer.GroupDefinition, key string) output.Cell {
+	value := billing.GroupValue(definition, key)
+
+	if value == "" {
+		switch aws.StringValue(definition.Type) {
+		case costexplorer.GroupDefinitionTypeTag:
+			return output.Cell{Text: UNTAGGED}
+		case costexplorer.GroupDefinitionTypeCostCategory:
+			return output.Cell{Text: UNCATEGORIZED}
+		}
+	}
+
+	return textCell(value)
+}
+
+// sortGroups sorts groups by the value of their first key, the untagged or
+// uncategorized group last.
+func sortGroups(definition *costexplorer.GroupDefinition, groups []*costexplorer.Group) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		a := billing.GroupValue(definition, aws.StringValue(groups[i].Keys[0]))
+		b := billing.GroupValue(definition, aws.StringValue(groups[j].Keys[0]))
+
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+
+		return a < b
+	})
+}
@@ -2,55 +2,228 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"slices"
 	"sort"
 	"strings"
-	"text/tabwriter"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/organizations"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/output"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List accounts",
-	Run:   runList,
+	RunE:  runList,
 }
 
+var (
+	listColumns     []string
+	listStatus      string
+	listJoinedSince string
+)
+
+var listColumnNames = []string{"name", "id", "email", "status", "joined-method", "joined", "ou", "tags", "suspended"}
+
 func init() {
+	listCmd.Flags().StringSliceVar(&listColumns, "columns", []string{"name", "id", "suspended"}, "Columns to show, any of "+strings.Join(listColumnNames, ", "))
+	listCmd.Flags().StringVar(&listStatus, "status", "", "Only list accounts with this status, one of active, suspended, pending-closure")
+	listCmd.Flags().StringVar(&listJoinedSince, "joined-since", "", "Only list accounts that joined on or after this date (YYYY-MM-DD)")
+
+	addAccountFilterFlags(listCmd)
+
 	rootCmd.AddCommand(listCmd)
 }
 
-func runList(cmd *cobra.Command, args []string) {
-	result, err := organizationsSvc.ListAccounts(&organizations.ListAccountsInput{})
+// accountTags returns the tags of the accounts by account id.
+func accountTags(accounts []*organizations.Account) (map[string]map[string]string, error) {
+	var mu sync.Mutex
+	tags := map[string]map[string]string{}
+
+	var g errgroup.Group
+	g.SetLimit(MAX_CONCURRENCY)
+
+	for _, account := range accounts {
+		id := aws.StringValue(account.Id)
+
+		g.Go(func() error {
+			t, err := billing.ListTagsForResource(backend.Accounts, id)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			tags[id] = t
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// accountPaths returns the path of the organizational unit of the accounts
+// by account id.
+func accountPaths() (map[string]string, error) {
+	root, err := billing.DescribeOrganizationTree(backend.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]string{}
+
+	root.Walk(func(ou *billing.OrganizationalUnit) {
+		for _, account := range ou.Accounts {
+			paths[aws.StringValue(account.Id)] = strings.Join(ou.Path(), "/")
+		}
+	})
+
+	return paths, nil
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	accounts, err := billing.ListAccounts(backend.Accounts)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	accounts, err = filterAccountList(accounts)
+	if err != nil {
+		return err
+	}
+
+	if listStatus != "" {
+		status := strings.ToUpper(strings.ReplaceAll(listStatus, "-", "_"))
+		if !slices.Contains(organizations.AccountStatus_Values(), status) {
+			return fmt.Errorf("unknown status %q, must be one of active, suspended, pending-closure", listStatus)
+		}
+
+		filtered := []*organizations.Account{}
+		for _, account := range accounts {
+			if aws.StringValue(account.Status) == status {
+				filtered = append(filtered, account)
+			}
+		}
+		accounts = filtered
+	}
+
+	if listJoinedSince != "" {
+		since, err := time.Parse("2006-01-02", listJoinedSince)
+		if err != nil {
+			return fmt.Errorf("invalid --joined-since: %s", err)
+		}
+
+		filtered := []*organizations.Account{}
+		for _, account := range accounts {
+			if !aws.TimeValue(account.JoinedTimestamp).Before(since) {
+				filtered = append(filtered, account)
+			}
+		}
+		accounts = filtered
 	}
 
-	accounts := result.Accounts
 	sort.Slice(accounts, func(i, j int) bool {
 		return *accounts[i].Name < *accounts[j].Name
 	})
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	var tags map[string]map[string]string
+	var paths map[string]string
+
+	type listColumn struct {
+		column output.Column
+		cell   func(*organizations.Account) output.Cell
+	}
+
+	available := map[string]listColumn{
+		"name": {NAME_COLUMN, func(a *organizations.Account) output.Cell {
+			return textCell(aws.StringValue(a.Name))
+		}},
+		"id": {ID_COLUMN, func(a *organizations.Account) output.Cell {
+			return textCell(aws.StringValue(a.Id))
+		}},
+		"email": {EMAIL_COLUMN, func(a *organizations.Account) output.Cell {
+			return textCell(aws.StringValue(a.Email))
+		}},
+		"status": {STATUS_COLUMN, func(a *organizations.Account) output.Cell {
+			return textCell(aws.StringValue(a.Status))
+		}},
+		"joined-method": {JOINED_BY_COLUMN, func(a *organizations.Account) output.Cell {
+			return textCell(aws.StringValue(a.JoinedMethod))
+		}},
+		"joined": {JOINED_COLUMN, func(a *organizations.Account) output.Cell {
+			if a.JoinedTimestamp == nil {
+				return output.Cell{}
+			}
+			joined := a.JoinedTimestamp.UTC()
+			return output.Cell{Text: joined.Format("2006-01-02"), Value: joined.Format(time.RFC3339)}
+		}},
+		"ou": {OU_COLUMN, func(a *organizations.Account) output.Cell {
+			return textCell(paths[aws.StringValue(a.Id)])
+		}},
+		"tags": {TAGS_COLUMN, func(a *organizations.Account) output.Cell {
+			t := tags[aws.StringValue(a.Id)]
+			return output.Cell{Text: output.FormatMap(t), Value: t}
+		}},
+		"suspended": {SUSPENDED_COLUMN, func(a *organizations.Account) output.Cell {
+			return suspendedCell(aws.StringValue(a.Status))
+		}},
+	}
+
+	selected := []listColumn{}
+	columns := []output.Column{}
+
+	for _, name := range listColumns {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		c, ok := available[name]
+		if !ok {
+			return fmt.Errorf("unknown column %q, must be one of %s", name, strings.Join(listColumnNames, ", "))
+		}
+
+		switch {
+		case name == "tags" && tags == nil:
+			tags, err = accountTags(accounts)
+		case name == "ou" && paths == nil:
+			paths, err = accountPaths()
+		}
+		if err != nil {
+			return err
+		}
 
-	fmt.Fprintln(w, strings.Join([]string{
-		NAME_TITLE,
-		ID_TITLE,
-		SUSPENDED_TITLE,
-	}, "\t"))
+		selected = append(selected, c)
+		columns = append(columns, c.column)
+	}
+
+	if len(selected) == 0 {
+		return fmt.Errorf("no columns selected")
+	}
+
+	t := output.NewTable(columns...)
 
 	for _, account := range accounts {
-		suspended := "NO"
-		if *account.Status == "SUSPENDED" {
-			suspended = "YES"
+		cells := []output.Cell{}
+		for _, c := range selected {
+			cells = append(cells, c.cell(account))
 		}
 
-		s := fmt.Sprintf("%s\t%s\t%s", *account.Name, *account.Id, suspended)
+		t.AddRow(cells...)
+	}
 
-		fmt.Fprintln(w, s)
+	if err := writeTable(cmd, t); err != nil {
+		return err
 	}
 
-	w.Flush()
+	return nil
 }
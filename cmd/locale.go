@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
+)
+
+var (
+	localeName string
+)
+
+func init() {
+	defaultLocale := money.DefaultLocale
+	if s, ok := os.LookupEnv("ABU_LOCALE"); ok {
+		defaultLocale = s
+	}
+
+	rootCmd.PersistentFlags().StringVar(&localeName, "locale", defaultLocale, "Locale to format amounts with, one of "+strings.Join(money.Locales(), ", ")+", defaults to $ABU_LOCALE")
+}
+
+// setLocale applies the selected locale to amounts in tables and numbers in
+// CSV output.
+func setLocale() error {
+	l, err := money.LookupLocale(localeName)
+	if err != nil {
+		return err
+	}
+
+	money.SetLocale(l)
+	output.DecimalSeparator = l.Decimal
+
+	return nil
+}
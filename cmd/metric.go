@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type Metric struct {
+	Usage    string
+	Forecast string
+}
+
+var METRICS = map[string]Metric{
+	"unblended":     {Usage: "UnblendedCost", Forecast: "UNBLENDED_COST"},
+	"blended":       {Usage: "BlendedCost", Forecast: "BLENDED_COST"},
+	"amortized":     {Usage: "AmortizedCost", Forecast: "AMORTIZED_COST"},
+	"net-unblended": {Usage: "NetUnblendedCost", Forecast: "NET_UNBLENDED_COST"},
+	"net-amortized": {Usage: "NetAmortizedCost", Forecast: "NET_AMORTIZED_COST"},
+}
+
+var (
+	metricName string
+)
+
+func metricNames() []string {
+	names := []string{}
+	for name := range METRICS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func addMetricFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricName, "metric", "unblended", "Cost metric, one of "+strings.Join(metricNames(), ", "))
+}
+
+func selectedMetric() (Metric, error) {
+	metric, ok := METRICS[metricName]
+	if !ok {
+		return Metric{}, fmt.Errorf("unknown metric %q, must be one of %s", metricName, strings.Join(metricNames(), ", "))
+	}
+
+	return metric, nil
+}
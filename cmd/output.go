@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
+)
+
+func writeTable(cmd *cobra.Command, t *output.Table) error {
+	return output.Write(cmd.OutOrStdout(), outputFormat, t)
+}
+
+func textCell(s string) output.Cell {
+	return output.Cell{Text: s, Value: s}
+}
+
+func yesNoCell(b bool) output.Cell {
+	if b {
+		return output.Cell{Text: "YES", Value: true}
+	}
+
+	return output.Cell{Text: "NO", Value: false}
+}
+
+func suspendedCell(status string) output.Cell {
+	return yesNoCell(status == "SUSPENDED")
+}
+
+// monthCell shows the month of an AWS date, with its year if the months
+// shown span more than one year.
+func monthCell(date string, withYear bool) (output.Cell, error) {
+	month, err := AWSMonthToString(date)
+	if err != nil {
+		return output.Cell{}, err
+	}
+
+	if withYear {
+		month += " " + date[:4]
+	}
+
+	return output.Cell{Text: month, Value: date}, nil
+}
+
+// MoneyColumns are a column for an amount in dollars, followed by a column
+// for its conversion into each selected currency.
+type MoneyColumns struct {
+	Key   string
+	Title string
+}
+
+func (c MoneyColumns) Columns() []output.Column {
+	columns := []output.Column{
+		{Key: c.Key + "_dollar", Title: strings.Join([]string{c.Title, DOLLAR}, " "), AlignRight: true},
+	}
+
+	for _, code := range selectedCurrencies() {
+		currency := money.LookupCurrency(code)
+		columns = append(columns, output.Column{
+			Key:        c.Key + "_estimated_" + currency.Name,
+			Title:      strings.Join([]string{c.Title, "(" + ESTIMATED + strings.TrimSpace(currency.Symbol) + ")"}, " "),
+			AlignRight: true,
+		})
+	}
+
+	return columns
+}
+
+// amounts holds an amount in dollars and its conversion into each selected
+// currency, keyed by currency code.
+type amounts map[string]money.Amount
+
+// convert converts dollars into each selected currency, using the rate of
+// the period if there is one.
+func convert(dollar money.Amount, period *costexplorer.DateInterval) (amounts, error) {
+	if dollar.Currency() != money.USD {
+		return nil, fmt.Errorf("cannot convert %s, expected an amount in %s", dollar, money.USD)
+	}
+
+	a := amounts{money.USD: dollar}
+
+	for _, currency := range selectedCurrencies() {
+		converted, err := money.Convert(dollar, currency, period)
+		if err != nil {
+			return nil, err
+		}
+		a[currency] = converted
+	}
+
+	return a, nil
+}
+
+func (a amounts) get(currency string) money.Amount {
+	if amount, ok := a[currency]; ok {
+		return amount
+	}
+
+	return money.Zero(currency)
+}
+
+func (a amounts) add(b amounts) (amounts, error) {
+	result := amounts{}
+
+	for _, currency := range append([]string{money.USD}, selectedCurrencies()...) {
+		sum, err := a.get(currency).Add(b.get(currency))
+		if err != nil {
+			return nil, err
+		}
+		result[currency] = sum
+	}
+
+	return result, nil
+}
+
+func (a amounts) sub(b amounts) (amounts, error) {
+	result := amounts{}
+
+	for _, currency := range append([]string{money.USD}, selectedCurrencies()...) {
+		difference, err := a.get(currency).Sub(b.get(currency))
+		if err != nil {
+			return nil, err
+		}
+		result[currency] = difference
+	}
+
+	return result, nil
+}
+
+func (a amounts) mul(factor decimal.Decimal) amounts {
+	result := amounts{}
+
+	for currency, amount := range a {
+		result[currency] = amount.Mul(factor)
+	}
+
+	return result
+}
+
+// div divides the amounts, rounded to cents, e.g. to average them.
+func (a amounts) div(divisor int64) amounts {
+	result := amounts{}
+
+	for currency, amount := range a {
+		result[currency] = money.NewAmount(amount.Decimal().DivRound(decimal.New(divisor, 0), 2), currency)
+	}
+
+	return result
+}
+
+func moneyCell(a money.Amount) output.Cell {
+	return output.Cell{Text: money.Format(a), Value: output.Number(a.Decimal().String())}
+}
+
+func moneyCells(a amounts) []output.Cell {
+	cells := []output.Cell{moneyCell(a.get(money.USD))}
+
+	for _, currency := range selectedCurrencies() {
+		cells = append(cells, moneyCell(a.get(currency)))
+	}
+
+	return cells
+}
+
+// missingMoneyCells are cells for amounts that could not be determined.
+func missingMoneyCells() []output.Cell {
+	cells := []output.Cell{{Text: MISSING}}
+
+	for range selectedCurrencies() {
+		cells = append(cells, output.Cell{Text: MISSING})
+	}
+
+	return cells
+}
@@ -7,74 +7,172 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/budgets"
-	"github.com/aws/aws-sdk-go/service/costexplorer"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/organizations"
 	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/output"
 )
 
 var (
 	NAME_TITLE      = "NAME"
 	ID_TITLE        = "ID"
 	MONTH_TITLE     = "MONTH"
+	DATE_TITLE      = "DATE"
+	ESTIMATED_TITLE = "EST."
 	SUSPENDED_TITLE = "SUSP."
 	SERVICE_TITLE   = "SERVICE"
 	REGION_TITLE    = "REGION"
+	URL_TITLE       = "URL"
+	EMAIL_TITLE     = "EMAIL"
+	STATUS_TITLE    = "STATUS"
+	JOINED_TITLE    = "JOINED"
+	JOINED_BY_TITLE = "JOINED BY"
+	OU_TITLE        = "OU"
+	TAGS_TITLE      = "TAGS"
+	KEY_TITLE       = "KEY"
+	RULES_TITLE     = "RULES"
+	DEFAULT_TITLE   = "DEFAULT"
+	VALUES_TITLE    = "VALUES"
+	EFFECTIVE_TITLE = "EFFECTIVE"
+	SCORE_TITLE     = "SCORE"
+	ACCOUNT_TITLE   = "ACCOUNT"
+	MONITOR_TITLE   = "MONITOR"
+	START_TITLE     = "START"
+	END_TITLE       = "END"
+	FEEDBACK_TITLE  = "FEEDBACK"
+	ACCOUNTS_TITLE  = "ACCOUNTS"
+	FORECASTS_TITLE = "FORECASTS"
+
+	DOLLAR        = "($)"
+	ESTIMATED     = "~"
+	MISSING       = "n/a"
+	PARTIAL       = "~"
+	NEW           = "new"
+	UNTAGGED      = "(untagged)"
+	UNCATEGORIZED = "(uncategorized)"
+	NO_FEEDBACK   = "(none)"
+	ONGOING       = "(ongoing)"
+
+	BILL      = "BILL"
+	COST      = "COST"
+	FORECAST  = "FORECAST"
+	BUDGET    = "BUDGET"
+	DELTA     = "Δ"
+	BASELINE  = "BASELINE"
+	PROJECTED = "PROJECTED"
+	EXPECTED  = "EXPECTED"
+	ACTUAL    = "ACTUAL"
+	IMPACT    = "IMPACT"
+	PERCENT   = "%"
+
+	MONTH_TO_DATE          = "MTD"
+	PREVIOUS_MONTH_TO_DATE = strings.Join([]string{"PREV.", MONTH_TO_DATE}, " ")
+	MONTH_TO_DATE_DELTA    = strings.Join([]string{MONTH_TO_DATE, DELTA}, " ")
+
+	FORECAST_LOWER = strings.Join([]string{FORECAST, "LOW"}, " ")
+	FORECAST_UPPER = strings.Join([]string{FORECAST, "HIGH"}, " ")
+
+	BUDGET_FORECAST_DELTA = strings.Join([]string{BUDGET, "/", FORECAST, DELTA}, " ")
+	BILL_FORECAST_DELTA   = strings.Join([]string{COST, "/", FORECAST, DELTA}, " ")
+)
 
-	DOLLAR         = "($)"
-	ESTIMATED_EURO = "(~€)"
-
-	BILL                      = "BILL"
-	BILL_DOLLAR_TITLE         = strings.Join([]string{BILL, DOLLAR}, " ")
-	BILL_ESTIMATED_EURO_TITLE = strings.Join([]string{BILL, ESTIMATED_EURO}, " ")
-
-	COST                      = "COST"
-	COST_DOLLAR_TITLE         = strings.Join([]string{COST, DOLLAR}, " ")
-	COST_ESTIMATED_EURO_TITLE = strings.Join([]string{COST, ESTIMATED_EURO}, " ")
-
-	FORECAST                      = "FORECAST"
-	FORECAST_DOLLAR_TITLE         = strings.Join([]string{FORECAST, DOLLAR}, " ")
-	FORECAST_ESTIMATED_EURO_TITLE = strings.Join([]string{FORECAST, ESTIMATED_EURO}, " ")
-
-	BUDGET                      = "BUDGET"
-	BUDGET_DOLLAR_TITLE         = strings.Join([]string{BUDGET, DOLLAR}, " ")
-	BUDGET_ESTIMATED_EURO_TITLE = strings.Join([]string{BUDGET, ESTIMATED_EURO}, " ")
-
-	DELTA                      = "Δ"
-	DELTA_DOLLAR_TITLE         = strings.Join([]string{DELTA, DOLLAR}, " ")
-	DELTA_ESTIMATED_EURO_TITLE = strings.Join([]string{DELTA, ESTIMATED_EURO}, " ")
-
-	BUDGET_FORECAST_DELTA_DOLLAR_TITLE       = strings.Join([]string{BUDGET, "/", FORECAST, DELTA, DOLLAR}, " ")
-	BUDGET_FORECAST_DELTA_EURO_TITLE         = strings.Join([]string{BUDGET, "/", FORECAST, DELTA, ESTIMATED_EURO}, " ")
-	BILL_FORECAST_DELTA_DOLLAR_TITLE         = strings.Join([]string{COST, "/", FORECAST, DELTA, DOLLAR}, " ")
-	BILL_FORECAST_DELTA_ESTIMATED_EURO_TITLE = strings.Join([]string{COST, "/", FORECAST, DELTA, ESTIMATED_EURO}, " ")
+var (
+	NAME_COLUMN      = output.Column{Key: "name", Title: NAME_TITLE}
+	ID_COLUMN        = output.Column{Key: "id", Title: ID_TITLE}
+	MONTH_COLUMN     = output.Column{Key: "month", Title: MONTH_TITLE}
+	DATE_COLUMN      = output.Column{Key: "date", Title: DATE_TITLE}
+	ESTIMATED_COLUMN = output.Column{Key: "estimated", Title: ESTIMATED_TITLE}
+	SUSPENDED_COLUMN = output.Column{Key: "suspended", Title: SUSPENDED_TITLE}
+	SERVICE_COLUMN   = output.Column{Key: "service", Title: SERVICE_TITLE}
+	REGION_COLUMN    = output.Column{Key: "region", Title: REGION_TITLE}
+	URL_COLUMN       = output.Column{Key: "url", Title: URL_TITLE}
+	PERCENT_COLUMN   = output.Column{Key: "delta_percent", Title: strings.Join([]string{DELTA, PERCENT}, " "), AlignRight: true}
+	EMAIL_COLUMN     = output.Column{Key: "email", Title: EMAIL_TITLE}
+	STATUS_COLUMN    = output.Column{Key: "status", Title: STATUS_TITLE}
+	JOINED_COLUMN    = output.Column{Key: "joined", Title: JOINED_TITLE}
+	JOINED_BY_COLUMN = output.Column{Key: "joined_method", Title: JOINED_BY_TITLE}
+	OU_COLUMN        = output.Column{Key: "ou", Title: OU_TITLE}
+	TAGS_COLUMN      = output.Column{Key: "tags", Title: TAGS_TITLE}
+	KEY_COLUMN       = output.Column{Key: "key", Title: KEY_TITLE}
+	RULES_COLUMN     = output.Column{Key: "rules", Title: RULES_TITLE, AlignRight: true}
+	DEFAULT_COLUMN   = output.Column{Key: "default", Title: DEFAULT_TITLE}
+	VALUES_COLUMN    = output.Column{Key: "values", Title: VALUES_TITLE}
+	EFFECTIVE_COLUMN = output.Column{Key: "effective", Title: EFFECTIVE_TITLE}
+	SCORE_COLUMN     = output.Column{Key: "score", Title: SCORE_TITLE, AlignRight: true}
+	ACCOUNT_COLUMN   = output.Column{Key: "account", Title: ACCOUNT_TITLE}
+	MONITOR_COLUMN   = output.Column{Key: "monitor", Title: MONITOR_TITLE}
+	START_COLUMN     = output.Column{Key: "start", Title: START_TITLE}
+	END_COLUMN       = output.Column{Key: "end", Title: END_TITLE}
+	FEEDBACK_COLUMN  = output.Column{Key: "feedback", Title: FEEDBACK_TITLE}
+	ACCOUNTS_COLUMN  = output.Column{Key: "accounts", Title: ACCOUNTS_TITLE, AlignRight: true}
+	FORECASTS_COLUMN = output.Column{Key: "forecasts", Title: FORECASTS_TITLE, AlignRight: true}
+
+	BILL_COLUMNS                  = MoneyColumns{Key: "bill", Title: BILL}
+	COST_COLUMNS                  = MoneyColumns{Key: "cost", Title: COST}
+	FORECAST_COLUMNS              = MoneyColumns{Key: "forecast", Title: FORECAST}
+	BUDGET_COLUMNS                = MoneyColumns{Key: "budget", Title: BUDGET}
+	DELTA_COLUMNS                 = MoneyColumns{Key: "delta", Title: DELTA}
+	BASELINE_COLUMNS              = MoneyColumns{Key: "baseline", Title: BASELINE}
+	PROJECTED_COLUMNS             = MoneyColumns{Key: "projected_cost", Title: PROJECTED}
+	EXPECTED_COLUMNS              = MoneyColumns{Key: "expected", Title: EXPECTED}
+	ACTUAL_COLUMNS                = MoneyColumns{Key: "actual", Title: ACTUAL}
+	IMPACT_COLUMNS                = MoneyColumns{Key: "impact", Title: IMPACT}
+	IMPACT_PERCENT_COLUMN         = output.Column{Key: "impact_percent", Title: strings.Join([]string{IMPACT, PERCENT}, " "), AlignRight: true}
+	BUDGET_FORECAST_DELTA_COLUMNS = MoneyColumns{Key: "budget_forecast_delta", Title: BUDGET_FORECAST_DELTA}
+	BILL_FORECAST_DELTA_COLUMNS   = MoneyColumns{Key: "bill_forecast_delta", Title: BILL_FORECAST_DELTA}
+
+	MONTH_TO_DATE_COLUMNS          = MoneyColumns{Key: "month_to_date", Title: MONTH_TO_DATE}
+	PREVIOUS_MONTH_TO_DATE_COLUMNS = MoneyColumns{Key: "previous_month_to_date", Title: PREVIOUS_MONTH_TO_DATE}
+	MONTH_TO_DATE_DELTA_COLUMNS    = MoneyColumns{Key: "month_to_date_delta", Title: MONTH_TO_DATE_DELTA}
 )
 
 var (
-	sess *session.Session
+	backend *billing.Backend
 
-	budgetSvc        *budgets.Budgets
-	ec2Svc           *ec2.EC2
-	costExplorerSvc  *costexplorer.CostExplorer
-	organizationsSvc *organizations.Organizations
+	outputFormat string
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "abu",
-	Short: "abu is a utility for AWS billing",
+	Use:               "abu",
+	Short:             "abu is a utility for AWS billing",
+	PersistentPreRunE: persistentPreRun,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
 }
 
 func init() {
-	sess = session.Must(session.NewSessionWithOptions(session.Options{
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", output.FormatTable, "Output format, one of "+strings.Join(output.Formats, ", "))
+}
+
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	if err := output.ValidFormat(outputFormat); err != nil {
+		return err
+	}
+
+	if err := setLocale(); err != nil {
+		return err
+	}
+
+	if backend != nil {
+		return nil
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
 		Config:            aws.Config{Region: aws.String("eu-west-1")},
 		SharedConfigState: session.SharedConfigEnable,
-	}))
+	})
+	if err != nil {
+		return err
+	}
+
+	backend = billing.NewAWSBackend(sess)
 
-	budgetSvc = budgets.New(sess)
-	ec2Svc = ec2.New(sess)
-	costExplorerSvc = costexplorer.New(sess)
-	organizationsSvc = organizations.New(sess)
+	return nil
+}
+
+// SetBackend overrides the AWS backend, e.g. with an in-memory fake.
+func SetBackend(b *billing.Backend) {
+	backend = b
 }
 
 func Execute() {
@@ -86,7 +184,7 @@ func Execute() {
 func AWSMonthToString(m string) (string, error) {
 	mp, err := time.Parse("2006-01-02", m)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
 	return mp.Month().String(), nil
@@ -0,0 +1,514 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/budgets"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/billing/fake"
+)
+
+// testBackend has two accounts with constant daily costs since October 2023,
+// seen on 15 March 2024. The costs of the first account are split into two
+// usage types. Next to a cost budget there is a usage budget in gigabytes.
+func testBackend() *fake.Backend {
+	f := &fake.Backend{
+		MasterAccountId: "111",
+		Accounts: []*organizations.Account{
+			{Id: aws.String("111"), Name: aws.String("master"), Status: aws.String("ACTIVE")},
+			{Id: aws.String("222"), Name: aws.String("dev"), Status: aws.String("SUSPENDED")},
+		},
+		Budgets: []*budgets.Budget{
+			{
+				BudgetName:      aws.String("costs"),
+				BudgetType:      aws.String(budgets.BudgetTypeCost),
+				TimeUnit:        aws.String(budgets.TimeUnitMonthly),
+				BudgetLimit:     &budgets.Spend{Amount: aws.String("300"), Unit: aws.String("USD")},
+				CalculatedSpend: &budgets.CalculatedSpend{ActualSpend: &budgets.Spend{Amount: aws.String("168"), Unit: aws.String("USD")}},
+			},
+			{
+				BudgetName:      aws.String("storage"),
+				BudgetType:      aws.String(budgets.BudgetTypeUsage),
+				TimeUnit:        aws.String(budgets.TimeUnitMonthly),
+				BudgetLimit:     &budgets.Spend{Amount: aws.String("500"), Unit: aws.String("GB")},
+				CalculatedSpend: &budgets.CalculatedSpend{ActualSpend: &budgets.Spend{Amount: aws.String("120"), Unit: aws.String("GB")}},
+			},
+		},
+		Forecasts: map[string]float64{"111": 100},
+		PageSize:  1,
+		Now: func() time.Time {
+			return time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+		},
+	}
+
+	for day := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC); day.Before(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)); day = day.AddDate(0, 0, 1) {
+		f.Costs = append(f.Costs,
+			fake.Cost{Date: day, AccountId: "111", Service: "Amazon EC2", Region: "eu-west-1", UsageType: "BoxUsage", Amount: 6},
+			fake.Cost{Date: day, AccountId: "111", Service: "Amazon EC2", Region: "eu-west-1", UsageType: "EBS:VolumeUsage", Amount: 4},
+			fake.Cost{Date: day, AccountId: "222", Service: "Amazon S3", Region: "us-east-1", UsageType: "TimedStorage", Amount: 2},
+		)
+	}
+
+	return f
+}
+
+// resetFlags sets all flags back to their defaults, as flags are bound to
+// package variables that outlive a single execution.
+func resetFlags(c *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			defaults := []string{}
+			if flag.DefValue != "[]" {
+				defaults = strings.Split(flag.DefValue[1:len(flag.DefValue)-1], ",")
+			}
+			slice.Replace(defaults)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+
+	for _, child := range c.Commands() {
+		resetFlags(child)
+	}
+}
+
+// run runs abu against the backend without currency conversion and returns
+// what it wrote.
+func run(b *billing.Backend, args ...string) (string, error) {
+	resetFlags(rootCmd)
+	rootCmd.PersistentFlags().Lookup("currency").Value.(pflag.SliceValue).Replace(nil)
+
+	// Slice flags append to their value once they have been set, so the
+	// ones given are cleared first.
+	if c, _, err := rootCmd.Find(args); err == nil {
+		for _, arg := range args {
+			name, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			if flag := c.Flags().Lookup(name); flag != nil {
+				if slice, ok := flag.Value.(pflag.SliceValue); ok {
+					slice.Replace(nil)
+				}
+			}
+		}
+	}
+
+	SetBackend(b)
+	defer SetBackend(nil)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(args)
+
+	err := rootCmd.Execute()
+
+	return out.String(), err
+}
+
+// execute runs abu against the fake with JSON output and decodes the rows it
+// writes.
+func execute(t *testing.T, f *fake.Backend, args ...string) []map[string]interface{} {
+	t.Helper()
+
+	out, err := run(f.Backend(), append(args, "--output=json")...)
+	if err != nil {
+		t.Fatalf("abu %v: %v", args, err)
+	}
+
+	rows := []map[string]interface{}{}
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("abu %v wrote invalid JSON: %v\n%s", args, err, out)
+	}
+
+	return rows
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		// costs are added to those of the test backend.
+		costs []fake.Cost
+		args  []string
+		want  []map[string]interface{}
+	}{
+		{
+			name: "bills",
+			args: []string{"bills", "--months=2"},
+			want: []map[string]interface{}{
+				{"month": "2024-02-01", "cost_dollar": 348.0},
+				{"month": "2024-01-01", "cost_dollar": 372.0},
+			},
+		},
+		{
+			name: "bills by account",
+			args: []string{"bills", "--months=1", "--group-by=account"},
+			want: []map[string]interface{}{
+				{"month": "2024-02-01", "linked_account": "111", "cost_dollar": 290.0},
+				{"month": "2024-02-01", "linked_account": "222", "cost_dollar": 58.0},
+			},
+		},
+		{
+			name: "accounts",
+			args: []string{"accounts"},
+			want: []map[string]interface{}{
+				{
+					"name":                          "dev",
+					"id":                            "222",
+					"bill_dollar":                   58.0,
+					"month_to_date_dollar":          28.0,
+					"previous_month_to_date_dollar": 28.0,
+					"month_to_date_delta_dollar":    0.0,
+					"forecast_dollar":               nil,
+					"forecast_lower_bound_dollar":   nil,
+					"forecast_upper_bound_dollar":   nil,
+					"bill_forecast_delta_dollar":    nil,
+					"suspended":                     true,
+				},
+				{
+					"name":                          "master",
+					"id":                            "111",
+					"bill_dollar":                   290.0,
+					"month_to_date_dollar":          140.0,
+					"previous_month_to_date_dollar": 140.0,
+					"month_to_date_delta_dollar":    0.0,
+					"forecast_dollar":               240.0,
+					"forecast_lower_bound_dollar":   224.0,
+					"forecast_upper_bound_dollar":   256.0,
+					"bill_forecast_delta_dollar":    -50.0,
+					"suspended":                     false,
+				},
+			},
+		},
+		{
+			name: "accounts filtered",
+			args: []string{"accounts", "--account=master"},
+			want: []map[string]interface{}{
+				{
+					"name":                          "master",
+					"id":                            "111",
+					"bill_dollar":                   290.0,
+					"month_to_date_dollar":          140.0,
+					"previous_month_to_date_dollar": 140.0,
+					"month_to_date_delta_dollar":    0.0,
+					"forecast_dollar":               240.0,
+					"forecast_lower_bound_dollar":   224.0,
+					"forecast_upper_bound_dollar":   256.0,
+					"bill_forecast_delta_dollar":    -50.0,
+					"suspended":                     false,
+				},
+			},
+		},
+		{
+			name: "change",
+			args: []string{"change", "--by=account", "--baseline=previous", "--lookback=1", "--top=0"},
+			want: []map[string]interface{}{
+				{"name": "master", "id": "111", "cost_dollar": 290.0, "baseline_dollar": 310.0, "delta_dollar": -20.0, "delta_percent": -6.45},
+				{"name": "dev", "id": "222", "cost_dollar": 58.0, "baseline_dollar": 62.0, "delta_dollar": -4.0, "delta_percent": -6.45},
+			},
+		},
+		{
+			// A dollar more on the first day projects to 31/14 dollars.
+			name:  "change month to date",
+			costs: []fake.Cost{{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), AccountId: "222", Service: "Amazon S3", Region: "us-east-1", Amount: 1}},
+			args:  []string{"change", "--by=account", "--baseline=previous", "--lookback=1", "--month-to-date", "--top=0"},
+			want: []map[string]interface{}{
+				{"name": "master", "id": "111", "projected_cost_dollar": 310.0, "baseline_dollar": 290.0, "delta_dollar": 20.0, "delta_percent": 6.9},
+				{"name": "dev", "id": "222", "projected_cost_dollar": 64.21, "baseline_dollar": 58.0, "delta_dollar": 6.21, "delta_percent": 10.71},
+			},
+		},
+		{
+			name: "change against the average",
+			args: []string{"change", "--by=account", "--baseline=average", "--lookback=3", "--top=0"},
+			want: []map[string]interface{}{
+				{"name": "master", "id": "111", "cost_dollar": 290.0, "baseline_dollar": 306.67, "delta_dollar": -16.67, "delta_percent": -5.44},
+				{"name": "dev", "id": "222", "cost_dollar": 58.0, "baseline_dollar": 61.33, "delta_dollar": -3.33, "delta_percent": -5.43},
+			},
+		},
+		{
+			// Account and service take the fewest values, so the costs are
+			// split by them and requested grouped by usage type and region.
+			name: "change by more than two keys",
+			args: []string{"change", "--by=usage-type,account,service,region", "--baseline=previous", "--lookback=1"},
+			want: []map[string]interface{}{
+				{"usage_type": "BoxUsage", "name": "master", "id": "111", "service": "Amazon EC2", "region": "eu-west-1", "cost_dollar": 174.0, "baseline_dollar": 186.0, "delta_dollar": -12.0, "delta_percent": -6.45},
+				{"usage_type": "EBS:VolumeUsage", "name": "master", "id": "111", "service": "Amazon EC2", "region": "eu-west-1", "cost_dollar": 116.0, "baseline_dollar": 124.0, "delta_dollar": -8.0, "delta_percent": -6.45},
+				{"usage_type": "TimedStorage", "name": "dev", "id": "222", "service": "Amazon S3", "region": "us-east-1", "cost_dollar": 58.0, "baseline_dollar": 62.0, "delta_dollar": -4.0, "delta_percent": -6.45},
+			},
+		},
+		{
+			name: "tree",
+			args: []string{"tree"},
+			want: []map[string]interface{}{
+				{"name": "Root", "id": "r-root", "bill_dollar": 348.0, "month_to_date_dollar": 168.0, "forecast_dollar": 240.0, "accounts": 2.0, "forecasts": 1.0},
+				{"name": "dev", "id": "222", "bill_dollar": 58.0, "month_to_date_dollar": 28.0, "forecast_dollar": nil, "accounts": 1.0, "forecasts": 0.0},
+				{"name": "master", "id": "111", "bill_dollar": 290.0, "month_to_date_dollar": 140.0, "forecast_dollar": 240.0, "accounts": 1.0, "forecasts": 1.0},
+			},
+		},
+		{
+			name: "budgets",
+			args: []string{"budgets"},
+			want: []map[string]interface{}{
+				{"name": "costs", "budget_dollar": 300.0, "cost_dollar": 168.0, "forecast_dollar": 0.0, "budget_forecast_delta_dollar": -300.0},
+			},
+		},
+		{
+			name: "list",
+			args: []string{"list", "--status=active"},
+			want: []map[string]interface{}{
+				{"name": "master", "id": "111", "suspended": false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := testBackend()
+			f.Costs = append(f.Costs, tt.costs...)
+
+			got := execute(t, f, tt.args...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("abu %v = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// failingForecasts fails every forecast with the given error.
+type failingForecasts struct {
+	err error
+}
+
+func (f failingForecasts) GetCostForecast(*costexplorer.GetCostForecastInput) (*costexplorer.GetCostForecastOutput, error) {
+	return nil, f.err
+}
+
+// emptyForecasts returns forecasts without any results.
+type emptyForecasts struct{}
+
+func (emptyForecasts) GetCostForecast(*costexplorer.GetCostForecastInput) (*costexplorer.GetCostForecastOutput, error) {
+	return &costexplorer.GetCostForecastOutput{}, nil
+}
+
+func TestEmptyForecast(t *testing.T) {
+	b := testBackend().Backend()
+	b.Forecasts = emptyForecasts{}
+
+	out, err := run(b, "accounts", "--account=master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out, "No forecast for master (111): Cost Explorer returned no forecast") {
+		t.Errorf("abu accounts = %q, want master without a forecast", out)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	throttled := testBackend().Backend()
+	throttled.Forecasts = failingForecasts{err: awserr.New("ThrottlingException", "rate exceeded", nil)}
+
+	budgeted := testBackend()
+	budgeted.Budgets = []*budgets.Budget{
+		{
+			BudgetName: aws.String("planned"),
+			BudgetType: aws.String(budgets.BudgetTypeCost),
+			TimeUnit:   aws.String(budgets.TimeUnitMonthly),
+			PlannedBudgetLimits: map[string]*budgets.Spend{
+				"1709251200": {Amount: aws.String("100"), Unit: aws.String("USD")},
+			},
+		},
+		{
+			BudgetName:     aws.String("auto"),
+			BudgetType:     aws.String(budgets.BudgetTypeCost),
+			TimeUnit:       aws.String(budgets.TimeUnitMonthly),
+			BudgetLimit:    &budgets.Spend{Amount: aws.String("100"), Unit: aws.String("USD")},
+			AutoAdjustData: &budgets.AutoAdjustData{AutoAdjustType: aws.String(budgets.AutoAdjustTypeHistorical)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		backend *billing.Backend
+		args    []string
+	}{
+		{
+			name:    "unknown granularity",
+			backend: testBackend().Backend(),
+			args:    []string{"bills", "--granularity=hourly"},
+		},
+		{
+			name:    "cost filter on list",
+			backend: testBackend().Backend(),
+			args:    []string{"list", "--service=Amazon EC2"},
+		},
+		{
+			name:    "too many requests",
+			backend: testBackend().Backend(),
+			args:    []string{"change", "--by=usage-type,account,service,region", "--max-requests=3"},
+		},
+		{
+			name:    "limit of a planned budget",
+			backend: budgeted.Backend(),
+			args:    []string{"budgets", "update", "planned", "--limit=200"},
+		},
+		{
+			name:    "period of an auto-adjusting budget",
+			backend: budgeted.Backend(),
+			args:    []string{"budgets", "update", "auto", "--period=quarterly"},
+		},
+		{
+			name:    "structured output of a budget change",
+			backend: testBackend().Backend(),
+			args:    []string{"budgets", "delete", "costs", "--output=json"},
+		},
+		{
+			name:    "forecast failure",
+			backend: throttled,
+			args:    []string{"accounts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := run(tt.backend, tt.args...); err == nil {
+				t.Errorf("abu %v succeeded, want an error", tt.args)
+			}
+		})
+	}
+}
+
+func TestAnomalies(t *testing.T) {
+	f := testBackend()
+	f.Costs = append(f.Costs, fake.Cost{Date: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), AccountId: "111", Service: "Amazon EC2", Region: "eu-west-1", UsageType: "BoxUsage", Amount: 10.1})
+
+	want := []map[string]interface{}{
+		{"date": "2024-03-10", "name": "master", "id": "111", "expected_dollar": 10.0, "actual_dollar": 20.1, "delta_dollar": 10.1, "score": nil},
+	}
+
+	args := []string{"anomalies", "--by=account", "--days=7"}
+	if got := execute(t, f, args...); !reflect.DeepEqual(got, want) {
+		t.Errorf("abu %v = %v, want %v", args, got, want)
+	}
+}
+
+func TestBudgetUpdatePeriod(t *testing.T) {
+	f := testBackend()
+	f.Budgets = []*budgets.Budget{
+		{
+			BudgetName:  aws.String("monthly"),
+			BudgetType:  aws.String(budgets.BudgetTypeCost),
+			TimeUnit:    aws.String(budgets.TimeUnitMonthly),
+			TimePeriod:  &budgets.TimePeriod{Start: aws.Time(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))},
+			BudgetLimit: &budgets.Spend{Amount: aws.String("100"), Unit: aws.String("USD")},
+		},
+	}
+
+	if _, err := run(f.Backend(), "budgets", "update", "monthly", "--period=quarterly"); err != nil {
+		t.Fatal(err)
+	}
+
+	budget := f.Budgets[0]
+	if aws.StringValue(budget.TimeUnit) != budgets.TimeUnitQuarterly || budget.TimePeriod != nil {
+		t.Errorf("budget = %v, want a quarterly budget without the old time period", budget)
+	}
+}
+
+func TestBillsAcrossYears(t *testing.T) {
+	tests := []struct {
+		months string
+		want   []string
+	}{
+		{months: "2", want: []string{"February", "January"}},
+		{months: "4", want: []string{"February 2024", "January 2024", "December 2023", "November 2023"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.months, func(t *testing.T) {
+			out, err := run(testBackend().Backend(), "bills", "--months="+tt.months)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(out, "\n")
+			if len(lines) < len(tt.want)+1 {
+				t.Fatalf("abu bills --months=%s = %q, want %d months", tt.months, out, len(tt.want))
+			}
+			for i, month := range tt.want {
+				if got, _, _ := strings.Cut(lines[i+1], "  "); got != month {
+					t.Errorf("row %d shows %q, want %q", i, got, month)
+				}
+			}
+		})
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	t.Setenv("ABU_SWITCH_ROLE_NAME", "admin")
+
+	f := testBackend()
+	f.Accounts = append(f.Accounts, &organizations.Account{Id: aws.String("333"), Name: aws.String("master"), Status: aws.String("ACTIVE")})
+
+	want := []map[string]interface{}{
+		{"name": "master", "id": "111", "url": "https://signin.aws.amazon.com/switchrole?account=111&roleName=admin&displayName=master-111"},
+		{"name": "master", "id": "333", "url": "https://signin.aws.amazon.com/switchrole?account=333&roleName=admin&displayName=master-333"},
+	}
+
+	if got := execute(t, f, "switch", "master"); !reflect.DeepEqual(got, want) {
+		t.Errorf("abu switch master = %v, want %v", got, want)
+	}
+}
+
+func TestSwitchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		roleName string
+		args     []string
+	}{
+		{name: "no account", roleName: "admin", args: []string{"switch"}},
+		{name: "no role name", args: []string{"switch", "master", "--output=json"}},
+		{name: "suspended account", roleName: "admin", args: []string{"switch", "dev", "--output=json"}},
+		{name: "unknown account", roleName: "admin", args: []string{"switch", "prod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ABU_SWITCH_ROLE_NAME", tt.roleName)
+
+			if out, err := run(testBackend().Backend(), tt.args...); err == nil {
+				t.Errorf("abu %v = %q, want an error", tt.args, out)
+			}
+		})
+	}
+}
+
+func TestBudgetCreateInLimitCurrency(t *testing.T) {
+	f := testBackend()
+
+	out, err := run(f.Backend(), "budgets", "create", "euros", "--limit=100", "--limit-currency=EUR", "--exchange-rate=EUR=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget := f.Budgets[len(f.Budgets)-1]
+	if aws.StringValue(budget.BudgetLimit.Amount) != "200.00" {
+		t.Errorf("limit = %v, want 200 dollars", budget.BudgetLimit)
+	}
+	if strings.Contains(out, "€") {
+		t.Errorf("abu budgets create = %q, want it in dollars only", out)
+	}
+}
@@ -2,55 +2,78 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 
-	"github.com/aws/aws-sdk-go/service/organizations"
 	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/output"
 )
 
 var switchCmd = &cobra.Command{
-	Use:   "switch",
+	Use:   "switch <account-name|account-id>",
 	Short: "Print the URL to switch accounts",
-	Run:   runSwitch,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSwitch,
 }
 
 func init() {
 	rootCmd.AddCommand(switchCmd)
 }
 
-func runSwitch(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
-		fmt.Println("Usage: abu switch <account-name|account-id>")
-		return
-	}
-
+func runSwitch(cmd *cobra.Command, args []string) error {
 	roleName := os.Getenv("ABU_SWITCH_ROLE_NAME")
 	if roleName == "" {
-		fmt.Println("Please set the environment variable ABU_SWITCH_ROLE_NAME to the name of the role to use for switching roles")
-		return
+		return fmt.Errorf("please set the environment variable ABU_SWITCH_ROLE_NAME to the name of the role to use for switching roles")
 	}
 
-	result, err := organizationsSvc.ListAccounts(&organizations.ListAccountsInput{})
+	accounts, err := billing.ListAccounts(backend.Accounts)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	t := output.NewTable(
+		NAME_COLUMN,
+		ID_COLUMN,
+		URL_COLUMN,
+	)
+	urls := []string{}
+
+	for _, account := range accounts {
+		if *account.Name != args[0] && *account.Id != args[0] {
+			continue
+		}
+
+		if *account.Status == "SUSPENDED" {
+			return fmt.Errorf("account %s (%s) is suspended", *account.Name, *account.Id)
+		}
+
+		url := fmt.Sprintf(
+			"https://signin.aws.amazon.com/switchrole?account=%s&roleName=%s&displayName=%s",
+			*account.Id,
+			roleName,
+			fmt.Sprintf("%s-%s", *account.Name, *account.Id),
+		)
+
+		t.AddRow(
+			textCell(*account.Name),
+			textCell(*account.Id),
+			textCell(url),
+		)
+		urls = append(urls, url)
 	}
 
-	for _, account := range result.Accounts {
-		if *account.Name == args[0] || *account.Id == args[0] {
-			if *account.Status == "SUSPENDED" {
-				fmt.Println("Account is suspended")
-				return
-			}
-
-			url := fmt.Sprintf(
-				"https://signin.aws.amazon.com/switchrole?account=%s&roleName=%s&displayName=%s",
-				*account.Id,
-				roleName,
-				fmt.Sprintf("%s-%s", *account.Name, *account.Id),
-			)
-
-			fmt.Println(url)
+	if len(urls) == 0 {
+		return fmt.Errorf("no account named %q or with that ID", args[0])
+	}
+
+	// The table format prints just the URLs, so they can be opened directly.
+	if outputFormat == output.FormatTable {
+		for _, url := range urls {
+			fmt.Fprintln(cmd.OutOrStdout(), url)
 		}
+		return nil
 	}
+
+	return writeTable(cmd, t)
 }
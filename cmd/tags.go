@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/money"
+	"github.com/giantswarm/abu/output"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags [key]",
+	Short: "Print cost allocation tag keys, or the costs by value of a tag",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runTags,
+}
+
+func init() {
+	addMetricFlag(tagsCmd)
+	addCostFilterFlags(tagsCmd)
+
+	rootCmd.AddCommand(tagsCmd)
+}
+
+// lastMonthToDate returns the period from the start of last month to today.
+func lastMonthToDate() *costexplorer.DateInterval {
+	now := backend.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return &costexplorer.DateInterval{
+		Start: aws.String(firstOfMonth.AddDate(0, -1, 0).Format("2006-01-02")),
+		End:   aws.String(today.Format("2006-01-02")),
+	}
+}
+
+func runTags(cmd *cobra.Command, args []string) error {
+	period := lastMonthToDate()
+
+	filter, err := costFilter(period)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		keys, err := billing.GetTags(backend.Costs, &costexplorer.GetTagsInput{
+			Filter:     filter,
+			TimePeriod: period,
+		})
+		if err != nil {
+			return err
+		}
+
+		t := output.NewTable(KEY_COLUMN)
+		for _, key := range keys {
+			t.AddRow(textCell(key))
+		}
+
+		if err := writeTable(cmd, t); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return writeGroupCosts(cmd, &costexplorer.GroupDefinition{
+		Type: aws.String(costexplorer.GroupDefinitionTypeTag),
+		Key:  aws.String(args[0]),
+	}, filter, period)
+}
+
+// writeGroupCosts writes last month's bill and the month to date by the
+// values of a group definition.
+func writeGroupCosts(cmd *cobra.Command, groupBy *costexplorer.GroupDefinition, filter *costexplorer.Expression, period *costexplorer.DateInterval) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	metric, err := selectedMetric()
+	if err != nil {
+		return err
+	}
+
+	result, err := billing.GetCostAndUsage(backend.Costs, &costexplorer.GetCostAndUsageInput{
+		Filter:      filter,
+		Granularity: aws.String("MONTHLY"),
+		GroupBy:     []*costexplorer.GroupDefinition{groupBy},
+		Metrics:     []*string{aws.String(metric.Usage)},
+		TimePeriod:  period,
+	})
+	if err != nil {
+		return err
+	}
+
+	type Line struct {
+		Key         string
+		Bill        amounts
+		MonthToDate amounts
+	}
+
+	lines := map[string]*Line{}
+	groups := []*costexplorer.Group{}
+
+	for _, resultByTime := range result.ResultsByTime {
+		for _, group := range resultByTime.Groups {
+			key := aws.StringValue(group.Keys[0])
+
+			line, ok := lines[key]
+			if !ok {
+				line = &Line{Key: key}
+				lines[key] = line
+				groups = append(groups, group)
+			}
+
+			dollar, err := money.CostExplorerGroupToDollar(group, metric.Usage)
+			if err != nil {
+				return err
+			}
+
+			converted, err := convert(dollar, resultByTime.TimePeriod)
+			if err != nil {
+				return err
+			}
+
+			if aws.StringValue(resultByTime.TimePeriod.Start) == aws.StringValue(period.Start) {
+				line.Bill = converted
+			} else {
+				line.MonthToDate = converted
+			}
+		}
+	}
+
+	sortGroups(groupBy, groups)
+
+	columns := []output.Column{groupColumn(groupBy)}
+	columns = append(columns, BILL_COLUMNS.Columns()...)
+	columns = append(columns, MONTH_TO_DATE_COLUMNS.Columns()...)
+
+	t := output.NewTable(columns...)
+
+	for _, group := range groups {
+		line := lines[aws.StringValue(group.Keys[0])]
+
+		cells := []output.Cell{groupCell(groupBy, line.Key)}
+		cells = append(cells, moneyCells(line.Bill)...)
+		cells = append(cells, moneyCells(line.MonthToDate)...)
+
+		t.AddRow(cells...)
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+	writeExchangeRate(cmd)
+
+	return nil
+}
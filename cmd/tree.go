@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/abu/billing"
+	"github.com/giantswarm/abu/output"
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Print the organizational units with the costs of their accounts",
+	Long: `Print the organizational units with the costs of their accounts.
+
+Forecasts of units marked with ~ leave out the accounts Cost Explorer could
+not forecast, the FORECASTS column tells how many of the ACCOUNTS below a unit
+are forecast.`,
+	RunE: runTree,
+}
+
+func init() {
+	addMetricFlag(treeCmd)
+	addCostFilterFlags(treeCmd)
+
+	rootCmd.AddCommand(treeCmd)
+}
+
+// treeNode sums the costs of the accounts below an organizational unit.
+// Forecasts of accounts Cost Explorer could not forecast are left out.
+type treeNode struct {
+	Bill        amounts
+	MonthToDate amounts
+	Forecast    amounts
+	Forecasted  int
+	Accounts    int
+}
+
+func (n *treeNode) add(line accountLine) error {
+	var err error
+
+	n.Bill, err = n.Bill.add(line.Bill)
+	if err != nil {
+		return err
+	}
+	n.MonthToDate, err = n.MonthToDate.add(line.MonthToDate)
+	if err != nil {
+		return err
+	}
+	if line.ForecastErr == nil {
+		n.Forecast, err = n.Forecast.add(line.Forecast)
+		if err != nil {
+			return err
+		}
+		n.Forecasted++
+	}
+	n.Accounts++
+
+	return nil
+}
+
+// forecastCells returns the forecast, marked as partial if some accounts
+// could not be forecast, or missing if none could.
+func (n *treeNode) forecastCells() []output.Cell {
+	if n.Forecasted == 0 {
+		return missingMoneyCells()
+	}
+
+	cells := moneyCells(n.Forecast)
+	if n.Forecasted < n.Accounts {
+		for i := range cells {
+			cells[i].Text = PARTIAL + cells[i].Text
+		}
+	}
+
+	return cells
+}
+
+// countCells tell how many accounts are below the unit and how many of them
+// are forecast.
+func (n *treeNode) countCells() []output.Cell {
+	return []output.Cell{
+		{Text: strconv.Itoa(n.Accounts), Value: n.Accounts},
+		{Text: strconv.Itoa(n.Forecasted), Value: n.Forecasted},
+	}
+}
+
+func runTree(cmd *cobra.Command, args []string) error {
+	if err := loadExchangeRate(cmd); err != nil {
+		return err
+	}
+
+	metric, err := selectedMetric()
+	if err != nil {
+		return err
+	}
+
+	root, err := billing.DescribeOrganizationTree(backend.Accounts)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := billing.ListAccounts(backend.Accounts)
+	if err != nil {
+		return err
+	}
+
+	accounts, err = filterAccountList(accounts)
+	if err != nil {
+		return err
+	}
+
+	lines := map[string]accountLine{}
+	accountList, err := accountLines(accounts, metric)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range accountList {
+		lines[line.Id] = line
+	}
+
+	columns := []output.Column{NAME_COLUMN, ID_COLUMN}
+	columns = append(columns, BILL_COLUMNS.Columns()...)
+	columns = append(columns, MONTH_TO_DATE_COLUMNS.Columns()...)
+	columns = append(columns, FORECAST_COLUMNS.Columns()...)
+	columns = append(columns, ACCOUNTS_COLUMN, FORECASTS_COLUMN)
+
+	t := output.NewTable(columns...)
+
+	var addUnit func(ou *billing.OrganizationalUnit, prefix string, childPrefix string) error
+	addUnit = func(ou *billing.OrganizationalUnit, prefix string, childPrefix string) error {
+		total := treeNode{}
+		for _, id := range ou.AccountIds() {
+			if line, ok := lines[id]; ok {
+				if err := total.add(line); err != nil {
+					return err
+				}
+			}
+		}
+
+		cells := []output.Cell{{Text: prefix + ou.Name, Value: ou.Name}, textCell(ou.Id)}
+		cells = append(cells, moneyCells(total.Bill)...)
+		cells = append(cells, moneyCells(total.MonthToDate)...)
+		cells = append(cells, total.forecastCells()...)
+		cells = append(cells, total.countCells()...)
+		t.AddRow(cells...)
+
+		// Units without any selected accounts are left out, accounts are
+		// listed before the units below.
+		children := []*billing.OrganizationalUnit{}
+		for _, child := range ou.Children {
+			for _, id := range child.AccountIds() {
+				if _, ok := lines[id]; ok {
+					children = append(children, child)
+					break
+				}
+			}
+		}
+
+		accountLines := []accountLine{}
+		for _, account := range ou.Accounts {
+			if line, ok := lines[*account.Id]; ok {
+				accountLines = append(accountLines, line)
+			}
+		}
+
+		sort.Slice(accountLines, func(i, j int) bool {
+			return accountLines[i].Name < accountLines[j].Name
+		})
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].Name < children[j].Name
+		})
+
+		for i, line := range accountLines {
+			branch, _ := treeBranch(i == len(accountLines)-1 && len(children) == 0)
+
+			node := treeNode{}
+			if err := node.add(line); err != nil {
+				return err
+			}
+
+			cells := []output.Cell{{Text: childPrefix + branch + line.Name, Value: line.Name}, textCell(line.Id)}
+			cells = append(cells, moneyCells(line.Bill)...)
+			cells = append(cells, moneyCells(line.MonthToDate)...)
+			cells = append(cells, node.forecastCells()...)
+			cells = append(cells, node.countCells()...)
+			t.AddRow(cells...)
+		}
+
+		for i, child := range children {
+			branch, indent := treeBranch(i == len(children)-1)
+			if err := addUnit(child, childPrefix+branch, childPrefix+indent); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := addUnit(root, "", ""); err != nil {
+		return err
+	}
+
+	if err := writeTable(cmd, t); err != nil {
+		return err
+	}
+
+	sortedLines := []accountLine{}
+	for _, line := range lines {
+		sortedLines = append(sortedLines, line)
+	}
+	sort.Slice(sortedLines, func(i, j int) bool {
+		return sortedLines[i].Name < sortedLines[j].Name
+	})
+	writeUnforecastable(cmd, sortedLines)
+
+	writeExchangeRate(cmd)
+
+	return nil
+}
+
+// treeBranch returns the branch drawn in front of a tree entry and the
+// indentation of the entries below it.
+func treeBranch(last bool) (string, string) {
+	if last {
+		return "└── ", "    "
+	}
+
+	return "├── ", "│   "
+}
@@ -0,0 +1,123 @@
+package money
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Amount is an exact amount of money in a currency. Arithmetic on amounts
+// in different currencies is refused, they have to be converted first.
+type Amount struct {
+	value    decimal.Decimal
+	currency string
+}
+
+func NewAmount(value decimal.Decimal, currency string) Amount {
+	return Amount{value: value, currency: currency}
+}
+
+func ParseAmount(s string, currency string) (Amount, error) {
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	return NewAmount(value, currency), nil
+}
+
+func Zero(currency string) Amount {
+	return NewAmount(decimal.Zero, currency)
+}
+
+func Dollar(value decimal.Decimal) Amount {
+	return NewAmount(value, USD)
+}
+
+func (a Amount) Currency() string {
+	return a.currency
+}
+
+func (a Amount) Decimal() decimal.Decimal {
+	return a.value
+}
+
+func (a Amount) Float64() float64 {
+	f, _ := a.value.Float64()
+	return f
+}
+
+func (a Amount) IsZero() bool {
+	return a.value.IsZero()
+}
+
+func (a Amount) Neg() Amount {
+	return NewAmount(a.value.Neg(), a.currency)
+}
+
+func (a Amount) sameCurrency(b Amount) error {
+	if a.currency != b.currency {
+		return fmt.Errorf("mixed currencies %s and %s", a.currency, b.currency)
+	}
+
+	return nil
+}
+
+func (a Amount) Add(b Amount) (Amount, error) {
+	if err := a.sameCurrency(b); err != nil {
+		return Amount{}, err
+	}
+
+	return NewAmount(a.value.Add(b.value), a.currency), nil
+}
+
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if err := a.sameCurrency(b); err != nil {
+		return Amount{}, err
+	}
+
+	return NewAmount(a.value.Sub(b.value), a.currency), nil
+}
+
+func (a Amount) Cmp(b Amount) (int, error) {
+	if err := a.sameCurrency(b); err != nil {
+		return 0, err
+	}
+
+	return a.value.Cmp(b.value), nil
+}
+
+// Mul scales the amount, e.g. to extrapolate it.
+func (a Amount) Mul(factor decimal.Decimal) Amount {
+	return NewAmount(a.value.Mul(factor), a.currency)
+}
+
+// Convert converts a dollar amount with the given rate.
+func (a Amount) Convert(rate Rate) (Amount, error) {
+	if a.currency != USD {
+		return Amount{}, fmt.Errorf("cannot convert %s with a rate from %s", a.currency, USD)
+	}
+
+	return NewAmount(a.value.Mul(rate.Value), rate.Currency), nil
+}
+
+// ToDollar converts an amount back into dollars with the given rate.
+func (a Amount) ToDollar(rate Rate) (Amount, error) {
+	if a.currency != rate.Currency {
+		return Amount{}, fmt.Errorf("cannot convert %s with a rate to %s", a.currency, rate.Currency)
+	}
+	if rate.Value.IsZero() {
+		return Amount{}, fmt.Errorf("no usable exchange rate for %s", rate.Currency)
+	}
+
+	return NewAmount(a.value.Div(rate.Value), USD), nil
+}
+
+// Round rounds the amount to cents.
+func (a Amount) Round() Amount {
+	return NewAmount(a.value.Round(2), a.currency)
+}
+
+func (a Amount) String() string {
+	return a.value.StringFixed(2) + " " + a.currency
+}
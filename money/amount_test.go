@@ -0,0 +1,115 @@
+package money
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func amount(s string, currency string) Amount {
+	return NewAmount(decimal.RequireFromString(s), currency)
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       Amount
+		b       Amount
+		sum     string
+		diff    string
+		cmp     int
+		wantErr bool
+	}{
+		{name: "cents stay exact", a: amount("0.1", USD), b: amount("0.2", USD), sum: "0.3", diff: "-0.1", cmp: -1},
+		{name: "negative", a: amount("-5", EUR), b: amount("-7.25", EUR), sum: "-12.25", diff: "2.25", cmp: 1},
+		{name: "equal", a: amount("3", EUR), b: amount("3.00", EUR), sum: "6", diff: "0", cmp: 0},
+		{name: "mixed currencies", a: amount("1", USD), b: amount("1", EUR), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum, sumErr := tt.a.Add(tt.b)
+			diff, diffErr := tt.a.Sub(tt.b)
+			cmp, cmpErr := tt.a.Cmp(tt.b)
+
+			if tt.wantErr {
+				if sumErr == nil || diffErr == nil || cmpErr == nil {
+					t.Errorf("Add, Sub and Cmp of %s and %s succeeded, want errors", tt.a, tt.b)
+				}
+				return
+			}
+			if sumErr != nil || diffErr != nil || cmpErr != nil {
+				t.Fatal(sumErr, diffErr, cmpErr)
+			}
+
+			if !sum.Decimal().Equal(decimal.RequireFromString(tt.sum)) || sum.Currency() != tt.a.Currency() {
+				t.Errorf("%s + %s = %s, want %s %s", tt.a, tt.b, sum, tt.sum, tt.a.Currency())
+			}
+			if !diff.Decimal().Equal(decimal.RequireFromString(tt.diff)) || diff.Currency() != tt.a.Currency() {
+				t.Errorf("%s - %s = %s, want %s %s", tt.a, tt.b, diff, tt.diff, tt.a.Currency())
+			}
+			if cmp != tt.cmp {
+				t.Errorf("Cmp(%s, %s) = %d, want %d", tt.a, tt.b, cmp, tt.cmp)
+			}
+		})
+	}
+}
+
+func TestAmountConvert(t *testing.T) {
+	rate := Rate{Currency: EUR, Value: decimal.RequireFromString("0.8"), Source: "test", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name    string
+		convert func() (Amount, error)
+		want    Amount
+		wantErr bool
+	}{
+		{name: "to the rate's currency", convert: func() (Amount, error) { return amount("12.50", USD).Convert(rate) }, want: amount("10", EUR)},
+		{name: "from another currency", convert: func() (Amount, error) { return amount("10", EUR).Convert(rate) }, wantErr: true},
+		{name: "back to dollars", convert: func() (Amount, error) { return amount("10", EUR).ToDollar(rate) }, want: amount("12.5", USD)},
+		{name: "back from another currency", convert: func() (Amount, error) { return amount("10", "GBP").ToDollar(rate) }, wantErr: true},
+		{name: "back with a zero rate", convert: func() (Amount, error) { return amount("10", EUR).ToDollar(Rate{Currency: EUR}) }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.convert()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("converted to %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Decimal().Equal(tt.want.Decimal()) || got.Currency() != tt.want.Currency() {
+				t.Errorf("converted to %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountRound(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "1.004", want: "1"},
+		{value: "1.005", want: "1.01"},
+		{value: "-1.005", want: "-1.01"},
+		{value: "62015.5000000000004001", want: "62015.5"},
+		{value: "-66.999999999999998", want: "-67"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := amount(tt.value, EUR).Round()
+			if !got.Decimal().Equal(decimal.RequireFromString(tt.want)) || got.Currency() != EUR {
+				t.Errorf("Round(%s) = %s, want %s EUR", tt.value, got, tt.want)
+			}
+		})
+	}
+}
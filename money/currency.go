@@ -0,0 +1,42 @@
+package money
+
+import (
+	"strings"
+)
+
+const (
+	USD = "USD"
+	EUR = "EUR"
+)
+
+// Currency describes how amounts in a currency are presented. The name is
+// used in field names of structured output.
+type Currency struct {
+	Code   string
+	Symbol string
+	Name   string
+}
+
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Symbol: "$", Name: "dollar"},
+	"EUR": {Code: "EUR", Symbol: "€", Name: "euro"},
+	"GBP": {Code: "GBP", Symbol: "£", Name: "pound"},
+	"CHF": {Code: "CHF", Symbol: "CHF ", Name: "franc"},
+	"JPY": {Code: "JPY", Symbol: "¥", Name: "yen"},
+}
+
+// LookupCurrency returns the currency with the given ISO 4217 code, making
+// up a presentation for currencies it does not know.
+func LookupCurrency(code string) Currency {
+	code = strings.ToUpper(code)
+
+	if currency, ok := currencies[code]; ok {
+		return currency
+	}
+
+	return Currency{
+		Code:   code,
+		Symbol: code + " ",
+		Name:   strings.ToLower(code),
+	}
+}
@@ -0,0 +1,371 @@
+package money
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rate is the amount of a currency one dollar buys, along with where and
+// when the rate was published.
+type Rate struct {
+	Currency string          `json:"currency"`
+	Value    decimal.Decimal `json:"value"`
+	Source   string          `json:"source"`
+	Date     time.Time       `json:"date"`
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("1 USD = %s %s (%s, %s)", r.Value.StringFixed(6), r.Currency, r.Source, r.Date.Format("2006-01-02"))
+}
+
+type ExchangeRateProvider interface {
+	Rate(currency string) (Rate, error)
+}
+
+// HistoricalExchangeRateProvider also knows the rates of past dates, and
+// returns all of them oldest first.
+type HistoricalExchangeRateProvider interface {
+	ExchangeRateProvider
+	Rates(currency string) ([]Rate, error)
+}
+
+const (
+	RateMethodAverage = "average"
+	RateMethodEnd     = "end"
+)
+
+var RateMethods = []string{RateMethodAverage, RateMethodEnd}
+
+// PeriodRate returns the rate for the period from start (inclusive) to end
+// (exclusive), either the average of the rates published in the period or
+// the last rate published before its end.
+func PeriodRate(rates []Rate, start, end time.Time, method string) (Rate, error) {
+	var last *Rate
+	sum := decimal.Zero
+	n := 0
+
+	for i, rate := range rates {
+		if !rate.Date.Before(end) {
+			break
+		}
+		last = &rates[i]
+
+		if !rate.Date.Before(start) {
+			sum = sum.Add(rate.Value)
+			n++
+		}
+	}
+
+	if last == nil {
+		return Rate{}, fmt.Errorf("no rate before %s", end.Format("2006-01-02"))
+	}
+
+	switch method {
+	case RateMethodAverage:
+		if n == 0 {
+			return *last, nil
+		}
+
+		return Rate{
+			Currency: last.Currency,
+			Value:    sum.Div(decimal.New(int64(n), 0)),
+			Source:   last.Source + " average",
+			Date:     start,
+		}, nil
+	case RateMethodEnd:
+		return *last, nil
+	default:
+		return Rate{}, fmt.Errorf("unknown rate method %q", method)
+	}
+}
+
+var (
+	// FallbackRates are used when no other rate is available, as of 2024-05-20.
+	FallbackRates = map[string]Rate{
+		"EUR": {Currency: "EUR", Value: decimal.RequireFromString("0.919606"), Source: "built-in fallback", Date: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+		"GBP": {Currency: "GBP", Value: decimal.RequireFromString("0.786919"), Source: "built-in fallback", Date: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+		"CHF": {Currency: "CHF", Value: decimal.RequireFromString("0.910755"), Source: "built-in fallback", Date: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+	}
+)
+
+// HTTPProvider fetches the latest rates from the open.er-api.com API. The
+// response is kept, so rates of several currencies need a single request.
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client
+
+	response *httpResponse
+	err      error
+}
+
+type httpResponse struct {
+	TimeLastUpdateUnix int64                      `json:"time_last_update_unix"`
+	Rates              map[string]decimal.Decimal `json:"rates"`
+}
+
+func NewHTTPProvider() *HTTPProvider {
+	return &HTTPProvider{
+		URL:    "https://open.er-api.com/v6/latest/USD",
+		Client: &http.Client{Timeout: time.Second * 1},
+	}
+}
+
+func (p *HTTPProvider) Rate(currency string) (Rate, error) {
+	if p.response == nil && p.err == nil {
+		p.response, p.err = p.fetch()
+	}
+	if p.err != nil {
+		return Rate{}, p.err
+	}
+
+	val, ok := p.response.Rates[currency]
+	if !ok {
+		return Rate{}, fmt.Errorf("no %s rate in response from %s", currency, p.URL)
+	}
+
+	return Rate{
+		Currency: currency,
+		Value:    val,
+		Source:   "open.er-api.com",
+		Date:     time.Unix(p.response.TimeLastUpdateUnix, 0).UTC(),
+	}, nil
+}
+
+func (p *HTTPProvider) fetch() (*httpResponse, error) {
+	r, err := p.Client.Get(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", r.Status, p.URL)
+	}
+
+	var target httpResponse
+	if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+		return nil, err
+	}
+
+	return &target, nil
+}
+
+// ECBFileProvider reads the rate from a European Central Bank reference rate
+// XML file, e.g. https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml.
+type ECBFileProvider struct {
+	Path string
+}
+
+type ecbEnvelope struct {
+	Cubes []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func (p *ECBFileProvider) Rate(currency string) (Rate, error) {
+	rates, err := p.Rates(currency)
+	if err != nil {
+		return Rate{}, err
+	}
+
+	return rates[len(rates)-1], nil
+}
+
+func (p *ECBFileProvider) Rates(currency string) ([]Rate, error) {
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(f).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	rates := []Rate{}
+
+	for _, cube := range envelope.Cubes {
+		date, err := time.Parse("2006-01-02", cube.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		// The ECB publishes how much of each currency one euro buys.
+		euroRates := map[string]decimal.Decimal{EUR: decimal.New(1, 0)}
+		for _, rate := range cube.Rates {
+			value, err := decimal.NewFromString(rate.Rate)
+			if err != nil {
+				return nil, err
+			}
+			euroRates[rate.Currency] = value
+		}
+
+		if euroRates[USD].IsZero() || euroRates[currency].IsZero() {
+			continue
+		}
+
+		rates = append(rates, Rate{
+			Currency: currency,
+			Value:    euroRates[currency].Div(euroRates[USD]),
+			Source:   "ECB " + filepath.Base(p.Path),
+			Date:     date,
+		})
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no USD to %s rate in %s", currency, p.Path)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+
+	return rates, nil
+}
+
+// StaticProvider always returns the same rates, e.g. ones given on the
+// command line.
+type StaticProvider struct {
+	Values map[string]decimal.Decimal
+	Source string
+}
+
+func (p *StaticProvider) Rate(currency string) (Rate, error) {
+	value, ok := p.Values[currency]
+	if !ok {
+		return Rate{}, fmt.Errorf("no static exchange rate for %s", currency)
+	}
+	if !value.IsPositive() {
+		return Rate{}, fmt.Errorf("invalid static exchange rate %v for %s", value, currency)
+	}
+
+	return Rate{
+		Currency: currency,
+		Value:    value,
+		Source:   p.Source,
+		Date:     time.Now().UTC().Truncate(24 * time.Hour),
+	}, nil
+}
+
+// CachedProvider stores the last rate per currency of the wrapped provider on
+// disk, and serves it while it is younger than MaxAge. When the wrapped
+// provider fails, or when Offline is set, the cached rate is returned
+// regardless of its age. Without a Path nothing is cached.
+type CachedProvider struct {
+	Provider ExchangeRateProvider
+	Path     string
+	MaxAge   time.Duration
+	Offline  bool
+}
+
+type cacheEntry struct {
+	Rate      Rate      `json:"rate"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// NewCachedProvider caches the rates in the user's cache directory, or not at
+// all if there is none, e.g. without $HOME in cron jobs and containers.
+func NewCachedProvider(provider ExchangeRateProvider, offline bool) *CachedProvider {
+	p := &CachedProvider{
+		Provider: provider,
+		MaxAge:   24 * time.Hour,
+		Offline:  offline,
+	}
+
+	if dir, err := os.UserCacheDir(); err == nil {
+		p.Path = filepath.Join(dir, "abu", "exchange-rates.json")
+	}
+
+	return p
+}
+
+func (p *CachedProvider) Rate(currency string) (Rate, error) {
+	entries, cacheErr := p.read()
+
+	entry, ok := entries[currency]
+	if cacheErr == nil && !ok {
+		cacheErr = fmt.Errorf("no cached %s rate", currency)
+	}
+
+	if cacheErr == nil && (p.Offline || time.Since(entry.FetchedAt) < p.MaxAge) {
+		return entry.Rate, nil
+	}
+
+	if p.Offline {
+		return Rate{}, fmt.Errorf("offline and no cached exchange rate: %w", cacheErr)
+	}
+
+	rate, err := p.Provider.Rate(currency)
+	if err != nil {
+		if cacheErr == nil {
+			return entry.Rate, nil
+		}
+
+		return Rate{}, err
+	}
+
+	if entries == nil {
+		entries = map[string]cacheEntry{}
+	}
+	entries[currency] = cacheEntry{Rate: rate, FetchedAt: time.Now()}
+
+	// Failing to cache the rate should not prevent it from being used.
+	_ = p.write(entries)
+
+	return rate, nil
+}
+
+func (p *CachedProvider) read() (map[string]cacheEntry, error) {
+	if p.Path == "" {
+		return nil, fmt.Errorf("no cache directory")
+	}
+
+	entries := map[string]cacheEntry{}
+
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+
+	for currency, entry := range entries {
+		if !entry.Rate.Value.IsPositive() {
+			return nil, fmt.Errorf("invalid cached %s exchange rate", currency)
+		}
+	}
+
+	return entries, nil
+}
+
+func (p *CachedProvider) write(entries map[string]cacheEntry) error {
+	if p.Path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p.Path, b, 0o644)
+}
@@ -0,0 +1,227 @@
+package money
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/shopspring/decimal"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func ecbRates(t *testing.T, currency string) []Rate {
+	t.Helper()
+
+	rates, err := (&ECBFileProvider{Path: "testdata/eurofxref-hist.xml"}).Rates(currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rates
+}
+
+func TestECBFileProviderRates(t *testing.T) {
+	tests := []struct {
+		currency string
+		// want are the rates by date, oldest first. The file has nothing on
+		// the weekend of 3 and 4 February and no GBP on 30 January.
+		want []string
+	}{
+		{
+			currency: EUR,
+			want:     []string{"2024-01-30 0.5", "2024-01-31 0.8", "2024-02-01 0.625", "2024-02-02 1", "2024-02-05 0.8"},
+		},
+		{
+			currency: "GBP",
+			want:     []string{"2024-01-31 0.68", "2024-02-01 0.5", "2024-02-02 0.9", "2024-02-05 0.8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			rates := ecbRates(t, tt.currency)
+
+			got := []string{}
+			for _, rate := range rates {
+				if rate.Currency != tt.currency {
+					t.Errorf("rate of %s has currency %s", rate.Date.Format("2006-01-02"), rate.Currency)
+				}
+				got = append(got, rate.Date.Format("2006-01-02")+" "+rate.Value.String())
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Rates(%s) = %v, want %v", tt.currency, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Rates(%s)[%d] = %s, want %s", tt.currency, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestECBFileProviderRate(t *testing.T) {
+	rate, err := (&ECBFileProvider{Path: "testdata/eurofxref-hist.xml"}).Rate(EUR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rate.Date.Equal(date(2, 5)) || !rate.Value.Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("Rate(EUR) = %s, want the rate of 2024-02-05", rate)
+	}
+}
+
+func TestECBFileProviderUnknownCurrency(t *testing.T) {
+	if _, err := (&ECBFileProvider{Path: "testdata/eurofxref-hist.xml"}).Rates("JPY"); err == nil {
+		t.Error("expected an error for a currency not in the file")
+	}
+}
+
+func TestPeriodRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		method   string
+		want     string
+		wantDate time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "average",
+			start:    date(2, 1),
+			end:      date(2, 3),
+			method:   RateMethodAverage,
+			want:     "0.8125",
+			wantDate: date(2, 1),
+		},
+		{
+			name:     "average of a month",
+			start:    date(1, 1),
+			end:      date(2, 1),
+			method:   RateMethodAverage,
+			want:     "0.65",
+			wantDate: date(1, 1),
+		},
+		{
+			name:     "end",
+			start:    date(1, 1),
+			end:      date(2, 1),
+			method:   RateMethodEnd,
+			want:     "0.8",
+			wantDate: date(1, 31),
+		},
+		{
+			name:     "average over a weekend",
+			start:    date(2, 3),
+			end:      date(2, 5),
+			method:   RateMethodAverage,
+			want:     "1",
+			wantDate: date(2, 2),
+		},
+		{
+			name:     "end over a weekend",
+			start:    date(2, 3),
+			end:      date(2, 5),
+			method:   RateMethodEnd,
+			want:     "1",
+			wantDate: date(2, 2),
+		},
+		{
+			name:    "before the first rate",
+			start:   date(1, 1),
+			end:     date(1, 30),
+			method:  RateMethodAverage,
+			wantErr: true,
+		},
+		{
+			name:    "unknown method",
+			start:   date(2, 1),
+			end:     date(2, 3),
+			method:  "median",
+			wantErr: true,
+		},
+	}
+
+	rates := ecbRates(t, EUR)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := PeriodRate(rates, tt.start, tt.end, tt.method)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("PeriodRate() = %s, want an error", rate)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !rate.Value.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("PeriodRate() = %s, want %s", rate.Value, tt.want)
+			}
+			if !rate.Date.Equal(tt.wantDate) {
+				t.Errorf("PeriodRate() date = %s, want %s", rate.Date.Format("2006-01-02"), tt.wantDate.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestConvertWithHistoricalRates(t *testing.T) {
+	SetRate(Rate{Currency: EUR, Value: decimal.RequireFromString("0.9"), Source: "test", Date: date(3, 1)})
+	SetHistoricalRates(EUR, ecbRates(t, EUR), RateMethodEnd)
+	t.Cleanup(func() { SetHistoricalRates(EUR, nil, RateMethodAverage) })
+
+	tests := []struct {
+		name   string
+		period *costexplorer.DateInterval
+		want   string
+	}{
+		{name: "current", want: "90"},
+		{name: "historical", period: &costexplorer.DateInterval{Start: aws.String("2024-02-01"), End: aws.String("2024-02-03")}, want: "100"},
+		{name: "before the first rate", period: &costexplorer.DateInterval{Start: aws.String("2024-01-01"), End: aws.String("2024-01-30")}, want: "90"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := Convert(Dollar(decimal.New(100, 0)), EUR, tt.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !converted.Decimal().Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Convert() = %s, want %s", converted.Decimal(), tt.want)
+			}
+		})
+	}
+
+	periods := CurrentRatePeriods(EUR)
+	if len(periods) != 1 || aws.StringValue(periods[0].Start) != "2024-01-01" {
+		t.Errorf("CurrentRatePeriods() = %v, want the period starting 2024-01-01", periods)
+	}
+}
+
+func TestCachedProviderWithoutCacheDir(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	static := &StaticProvider{Values: map[string]decimal.Decimal{EUR: decimal.RequireFromString("0.9")}, Source: "static"}
+
+	rate, err := NewCachedProvider(static, false).Rate(EUR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rate.Value.Equal(decimal.RequireFromString("0.9")) {
+		t.Errorf("Rate(EUR) = %s, want the rate of the provider", rate)
+	}
+
+	if _, err := NewCachedProvider(static, true).Rate(EUR); err == nil {
+		t.Error("expected an error offline without a cache")
+	}
+}
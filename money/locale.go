@@ -0,0 +1,67 @@
+package money
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const DefaultLocale = "en-US"
+
+// Locale describes how amounts are written. In the formats %s is replaced by
+// the currency symbol and %v by the number without its sign.
+type Locale struct {
+	Name           string
+	Thousand       string
+	Decimal        string
+	Format         string
+	FormatNegative string
+}
+
+var locales = map[string]Locale{
+	"en-US": {Name: "en-US", Thousand: ",", Decimal: ".", Format: "%s%v", FormatNegative: "-%s%v"},
+	"en-GB": {Name: "en-GB", Thousand: ",", Decimal: ".", Format: "%s%v", FormatNegative: "-%s%v"},
+	"de-DE": {Name: "de-DE", Thousand: ".", Decimal: ",", Format: "%v %s", FormatNegative: "-%v %s"},
+	"de-AT": {Name: "de-AT", Thousand: " ", Decimal: ",", Format: "%s %v", FormatNegative: "-%s %v"},
+	"de-CH": {Name: "de-CH", Thousand: "'", Decimal: ".", Format: "%s %v", FormatNegative: "%s-%v"},
+	"fr-FR": {Name: "fr-FR", Thousand: " ", Decimal: ",", Format: "%v %s", FormatNegative: "-%v %s"},
+	"nl-NL": {Name: "nl-NL", Thousand: ".", Decimal: ",", Format: "%s %v", FormatNegative: "%s -%v"},
+	"es-ES": {Name: "es-ES", Thousand: ".", Decimal: ",", Format: "%v %s", FormatNegative: "-%v %s"},
+}
+
+var locale = locales[DefaultLocale]
+
+// Locales returns the names of the known locales.
+func Locales() []string {
+	names := []string{}
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// LookupLocale returns the locale with the given name, accepting POSIX style
+// names like de_DE.UTF-8 as well.
+func LookupLocale(name string) (Locale, error) {
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.ReplaceAll(name, "_", "-")
+
+	for key, l := range locales {
+		if strings.EqualFold(key, name) {
+			return l, nil
+		}
+	}
+
+	return Locale{}, fmt.Errorf("unknown locale %q, must be one of %s", name, strings.Join(Locales(), ", "))
+}
+
+// SetLocale sets the locale used by Format.
+func SetLocale(l Locale) {
+	locale = l
+}
+
+func CurrentLocale() Locale {
+	return locale
+}
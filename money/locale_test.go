@@ -0,0 +1,100 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		locale string
+		amount Amount
+		want   string
+	}{
+		{locale: "en-US", amount: amount("1234.56", USD), want: "$1,234.56"},
+		{locale: "en-US", amount: amount("-1234.56", USD), want: "-$1,234.56"},
+		{locale: "en-US", amount: amount("1234.567", EUR), want: "€1,234.57"},
+		{locale: "en-US", amount: amount("1234.56", "CHF"), want: "CHF 1,234.56"},
+		{locale: "de-DE", amount: amount("1234.56", EUR), want: "1.234,56 €"},
+		{locale: "de-DE", amount: amount("-1234.56", EUR), want: "-1.234,56 €"},
+		{locale: "de-DE", amount: amount("1234.56", "CHF"), want: "1.234,56 CHF"},
+		{locale: "de-CH", amount: amount("1234.56", "CHF"), want: "CHF 1'234.56"},
+		{locale: "de-CH", amount: amount("-1234.56", "CHF"), want: "CHF-1'234.56"},
+		{locale: "fr-FR", amount: amount("1234567.8", EUR), want: "1\u202f234\u202f567,80 €"},
+		{locale: "nl-NL", amount: amount("-0.5", EUR), want: "€ -0,50"},
+	}
+
+	t.Cleanup(func() { SetLocale(locales[DefaultLocale]) })
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.amount.String(), func(t *testing.T) {
+			l, err := LookupLocale(tt.locale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SetLocale(l)
+
+			if got := Format(tt.amount); got != tt.want {
+				t.Errorf("Format(%s) = %q, want %q", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		locale  string
+		percent string
+		want    string
+	}{
+		{locale: "en-US", percent: "6.45", want: "+6.5%"},
+		{locale: "en-US", percent: "-6.44", want: "-6.4%"},
+		{locale: "de-DE", percent: "-6.45", want: "-6,5%"},
+		{locale: "de-DE", percent: "0", want: "0,0%"},
+	}
+
+	t.Cleanup(func() { SetLocale(locales[DefaultLocale]) })
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.percent, func(t *testing.T) {
+			SetLocale(locales[tt.locale])
+
+			if got := FormatPercent(decimal.RequireFromString(tt.percent)); got != tt.want {
+				t.Errorf("FormatPercent(%s) = %q, want %q", tt.percent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupLocale(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "de-DE", want: "de-DE"},
+		{name: "de_DE.UTF-8", want: "de-DE"},
+		{name: "EN_us", want: "en-US"},
+		{name: "xx-XX", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LookupLocale(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LookupLocale(%q) = %s, want an error", tt.name, l.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if l.Name != tt.want {
+				t.Errorf("LookupLocale(%q) = %s, want %s", tt.name, l.Name, tt.want)
+			}
+		})
+	}
+}
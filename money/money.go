@@ -1,216 +1,278 @@
 package money
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"strconv"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/budgets"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/leekchan/accounting"
+	"github.com/shopspring/decimal"
 )
 
 var (
-	dollarToEuro = 0.919606 // As of 2024-05-20, to use as fallback
+	rates = map[string]Rate{}
+
+	historicalRates = map[string][]Rate{}
+	rateMethod      = RateMethodAverage
+
+	// currentRatePeriods are the periods converted with the current rate for
+	// lack of a historical one, by currency and start of the period.
+	currentRatePeriods   = map[string]map[string]*costexplorer.DateInterval{}
+	currentRatePeriodsMu sync.Mutex
 )
 
-func init() {
-	attemptUpdateDollarToEuro()
+// SetRate sets the rate used to convert dollars to the rate's currency.
+func SetRate(r Rate) {
+	rates[r.Currency] = r
 }
 
-func attemptUpdateDollarToEuro() {
-	type response struct {
-		Rates map[string]float64 `json:"rates"`
-	}
+func CurrentRate(currency string) (Rate, bool) {
+	r, ok := rates[currency]
+	return r, ok
+}
 
-	httpClient := http.Client{Timeout: time.Second * 1}
+// SetHistoricalRates sets the rates used to convert the costs of past
+// periods to the given currency, see PeriodRate.
+func SetHistoricalRates(currency string, rs []Rate, method string) {
+	historicalRates[currency] = rs
+	rateMethod = method
 
-	r, err := httpClient.Get("https://open.er-api.com/v6/latest/USD")
-	if err != nil {
-		return
-	}
-	defer r.Body.Close()
+	currentRatePeriodsMu.Lock()
+	delete(currentRatePeriods, currency)
+	currentRatePeriodsMu.Unlock()
+}
 
-	var target response
-	if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
-		return
-	}
+func HistoricalRates(currency string) ([]Rate, string) {
+	return historicalRates[currency], rateMethod
+}
 
-	val, ok := target.Rates["EUR"]
-	if !ok {
-		return
+// CurrentRatePeriods returns the periods RateForPeriod had no historical rate
+// of the currency for, and used the current rate instead, oldest first.
+func CurrentRatePeriods(currency string) []*costexplorer.DateInterval {
+	currentRatePeriodsMu.Lock()
+	defer currentRatePeriodsMu.Unlock()
+
+	periods := []*costexplorer.DateInterval{}
+	for _, period := range currentRatePeriods[currency] {
+		periods = append(periods, period)
 	}
-	dollarToEuro = val
-}
 
-func DollarToEuro(d float64) float64 {
-	return d * dollarToEuro
+	sort.Slice(periods, func(i, j int) bool {
+		return aws.StringValue(periods[i].Start) < aws.StringValue(periods[j].Start)
+	})
+
+	return periods
 }
 
-func CostExplorerResultByTimeToDollar(resultByTime *costexplorer.ResultByTime) (float64, error) {
-	if resultByTime == nil {
-		return 0, nil
+// RateForPeriod returns the rate to convert costs of the given Cost Explorer
+// period to the currency with, falling back to the current rate for periods
+// before the first historical rate. Those are recorded, see
+// CurrentRatePeriods.
+func RateForPeriod(currency string, period *costexplorer.DateInterval) (Rate, error) {
+	rate, ok := rates[currency]
+	if !ok {
+		return Rate{}, fmt.Errorf("no exchange rate for %s", currency)
 	}
 
-	dollar, err := strconv.ParseFloat(*resultByTime.Total["UnblendedCost"].Amount, 64)
-	if err != nil {
-		return 0, err
+	if period == nil || len(historicalRates[currency]) == 0 {
+		return rate, nil
 	}
 
-	return dollar, nil
-}
+	start, err := time.Parse("2006-01-02", aws.StringValue(period.Start))
+	if err != nil {
+		return Rate{}, err
+	}
 
-func CostExplorerResultByTimeToEuro(resultByTime *costexplorer.ResultByTime) (float64, error) {
-	dollar, err := CostExplorerResultByTimeToDollar(resultByTime)
+	end, err := time.Parse("2006-01-02", aws.StringValue(period.End))
 	if err != nil {
-		return 0, err
+		return Rate{}, err
 	}
 
-	euro := DollarToEuro(dollar)
+	if !end.After(historicalRates[currency][0].Date) {
+		currentRatePeriodsMu.Lock()
+		if currentRatePeriods[currency] == nil {
+			currentRatePeriods[currency] = map[string]*costexplorer.DateInterval{}
+		}
+		currentRatePeriods[currency][aws.StringValue(period.Start)] = period
+		currentRatePeriodsMu.Unlock()
 
-	return euro, nil
+		return rate, nil
+	}
+
+	return PeriodRate(historicalRates[currency], start, end, rateMethod)
 }
 
-func CostExplorerGroupToDollar(group *costexplorer.Group) (float64, error) {
-	if group == nil {
-		return 0, nil
+// Convert converts a dollar amount to the currency, using the rate of the
+// period if there is one.
+func Convert(dollar Amount, currency string, period *costexplorer.DateInterval) (Amount, error) {
+	if currency == USD {
+		return dollar, nil
 	}
 
-	dollar, err := strconv.ParseFloat(*group.Metrics["UnblendedCost"].Amount, 64)
+	rate, err := RateForPeriod(currency, period)
 	if err != nil {
-		return 0, err
+		return Amount{}, err
 	}
 
-	return dollar, nil
+	return dollar.Convert(rate)
 }
 
-func CostExplorerGroupToEuro(group *costexplorer.Group) (float64, error) {
-	dollar, err := CostExplorerGroupToDollar(group)
-	if err != nil {
-		return 0, err
+// ToDollar converts an amount into dollars, using the rate of the period if
+// there is one.
+func ToDollar(a Amount, period *costexplorer.DateInterval) (Amount, error) {
+	if a.Currency() == USD {
+		return a, nil
 	}
 
-	euro := DollarToEuro(dollar)
+	rate, err := RateForPeriod(a.Currency(), period)
+	if err != nil {
+		return Amount{}, err
+	}
 
-	return euro, nil
+	return a.ToDollar(rate)
 }
 
-func ForecastResultToDollar(forecastResult *costexplorer.ForecastResult) (float64, error) {
-	if forecastResult == nil {
-		return 0, nil
+// parseAmount parses an amount returned by AWS, which is in dollars unless
+// a unit says otherwise.
+func parseAmount(amount *string, unit *string) (Amount, error) {
+	if amount == nil {
+		return Zero(USD), nil
 	}
 
-	dollar, err := strconv.ParseFloat(*forecastResult.MeanValue, 64)
-	if err != nil {
-		return 0, err
+	currency := USD
+	if aws.StringValue(unit) != "" {
+		currency = aws.StringValue(unit)
 	}
 
-	return dollar, nil
+	return ParseAmount(*amount, currency)
 }
 
-func ForecastResultToEuro(forecastResult *costexplorer.ForecastResult) (float64, error) {
-	dollar, err := ForecastResultToDollar(forecastResult)
-	if err != nil {
-		return 0, err
+func metricToDollar(metrics map[string]*costexplorer.MetricValue, metric string) (Amount, error) {
+	value, ok := metrics[metric]
+	if !ok || value.Amount == nil {
+		return Amount{}, fmt.Errorf("metric %q not found", metric)
 	}
 
-	euro := DollarToEuro(dollar)
-
-	return euro, nil
+	return parseAmount(value.Amount, value.Unit)
 }
 
-func BudgetSpendToDollar(budget *budgets.Budget) (float64, error) {
-	if budget == nil {
-		return 0, nil
+func spendToDollar(spend *budgets.Spend) (Amount, error) {
+	if spend == nil {
+		return Zero(USD), nil
 	}
 
-	dollar, err := strconv.ParseFloat(*budget.CalculatedSpend.ActualSpend.Amount, 64)
-	if err != nil {
-		return 0, err
+	return parseAmount(spend.Amount, spend.Unit)
+}
+
+func CostExplorerResultByTimeToDollar(resultByTime *costexplorer.ResultByTime, metric string) (Amount, error) {
+	if resultByTime == nil {
+		return Zero(USD), nil
 	}
 
-	return dollar, nil
+	return metricToDollar(resultByTime.Total, metric)
 }
 
-func BudgetSpendToEuro(budget *budgets.Budget) (float64, error) {
-	dollar, err := BudgetSpendToDollar(budget)
-	if err != nil {
-		return 0, err
+func CostExplorerGroupToDollar(group *costexplorer.Group, metric string) (Amount, error) {
+	if group == nil {
+		return Zero(USD), nil
 	}
 
-	euro := DollarToEuro(dollar)
-
-	return euro, nil
+	return metricToDollar(group.Metrics, metric)
 }
 
-func BudgetForecastToDollar(budget *budgets.Budget) (float64, error) {
-	if budget == nil {
-		return 0, nil
+func ForecastResultToDollar(forecastResult *costexplorer.ForecastResult) (Amount, error) {
+	if forecastResult == nil {
+		return Zero(USD), nil
 	}
 
-	dollar, err := strconv.ParseFloat(*budget.CalculatedSpend.ForecastedSpend.Amount, 64)
-	if err != nil {
-		return 0, err
+	return parseAmount(forecastResult.MeanValue, nil)
+}
+
+func ForecastLowerBoundToDollar(forecastResult *costexplorer.ForecastResult) (Amount, error) {
+	if forecastResult == nil {
+		return Zero(USD), nil
 	}
 
-	return dollar, nil
+	return parseAmount(forecastResult.PredictionIntervalLowerBound, nil)
 }
 
-func BudgetForecastToEuro(budget *budgets.Budget) (float64, error) {
-	dollar, err := BudgetForecastToDollar(budget)
-	if err != nil {
-		return 0, err
+func ForecastUpperBoundToDollar(forecastResult *costexplorer.ForecastResult) (Amount, error) {
+	if forecastResult == nil {
+		return Zero(USD), nil
 	}
 
-	euro := DollarToEuro(dollar)
-
-	return euro, nil
+	return parseAmount(forecastResult.PredictionIntervalUpperBound, nil)
 }
 
-func BudgetLimitToDollar(budget *budgets.Budget) (float64, error) {
-	if budget == nil {
-		return 0, nil
+func BudgetSpendToDollar(budget *budgets.Budget) (Amount, error) {
+	if budget == nil || budget.CalculatedSpend == nil {
+		return Zero(USD), nil
 	}
 
-	dollar, err := strconv.ParseFloat(*budget.BudgetLimit.Amount, 64)
-	if err != nil {
-		return 0, err
+	return spendToDollar(budget.CalculatedSpend.ActualSpend)
+}
+
+func BudgetForecastToDollar(budget *budgets.Budget) (Amount, error) {
+	if budget == nil || budget.CalculatedSpend == nil {
+		return Zero(USD), nil
 	}
 
-	return dollar, nil
+	return spendToDollar(budget.CalculatedSpend.ForecastedSpend)
 }
 
-func BudgetLimitToEuro(budget *budgets.Budget) (float64, error) {
-	dollar, err := BudgetLimitToDollar(budget)
-	if err != nil {
-		return 0, err
+func BudgetLimitToDollar(budget *budgets.Budget) (Amount, error) {
+	if budget == nil {
+		return Zero(USD), nil
 	}
 
-	euro := DollarToEuro(dollar)
-
-	return euro, nil
+	return spendToDollar(budget.BudgetLimit)
 }
 
-func TruncateString(s string) string {
-	return fmt.Sprintf("%10s", s)
+// AnomalyImpactToDollar returns the total impact of a Cost Anomaly Detection
+// finding, the cost above what was expected.
+func AnomalyImpactToDollar(anomaly *costexplorer.Anomaly) Amount {
+	if anomaly == nil || anomaly.Impact == nil {
+		return Zero(USD)
+	}
+
+	return Dollar(decimal.NewFromFloat(aws.Float64Value(anomaly.Impact.TotalImpact)))
 }
 
-func Float64DollarToStringDollar(f float64) string {
-	ac := accounting.Accounting{Symbol: "$", Precision: 2, Thousand: ",", Decimal: "."}
+// Format writes the amount the way the current locale does.
+func Format(a Amount) string {
+	c := LookupCurrency(a.Currency())
 
-	s := ac.FormatMoney(f)
-	s = TruncateString(s)
+	// Symbols like "CHF " carry their own space, which the locale's format
+	// may already provide.
+	symbol := c.Symbol
+	if strings.Contains(locale.Format, "%s ") || strings.Contains(locale.Format, " %s") {
+		symbol = strings.TrimSpace(symbol)
+	}
 
-	return s
-}
+	ac := accounting.Accounting{
+		Symbol:         symbol,
+		Precision:      2,
+		Thousand:       locale.Thousand,
+		Decimal:        locale.Decimal,
+		Format:         locale.Format,
+		FormatNegative: locale.FormatNegative,
+	}
 
-func Float64EuroToStringEuro(f float64) string {
-	ac := accounting.Accounting{Symbol: "€", Precision: 2, Thousand: ",", Decimal: "."}
+	return ac.FormatMoneyDecimal(a.Decimal())
+}
 
-	s := ac.FormatMoney(f)
-	s = TruncateString(s)
+// FormatPercent writes a percentage with one decimal and its sign the way the
+// current locale does.
+func FormatPercent(p decimal.Decimal) string {
+	s := p.StringFixed(1)
+	if p.Sign() > 0 {
+		s = "+" + s
+	}
 
-	return s
+	return strings.Replace(s, ".", locale.Decimal, 1) + "%"
 }
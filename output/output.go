@@ -0,0 +1,281 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+	FormatYAML  = "yaml"
+)
+
+var Formats = []string{FormatTable, FormatJSON, FormatCSV, FormatYAML}
+
+// DecimalSeparator is used for numbers in CSV output. If it is a comma, fields
+// are separated by semicolons, as spreadsheet applications expect.
+var DecimalSeparator = "."
+
+// Column is a column of a table. The title is used for human readable output,
+// the key is used as field name for structured output. Values of right
+// aligned columns are padded to the widest value of the column.
+type Column struct {
+	Key        string
+	Title      string
+	AlignRight bool
+}
+
+// Cell is a value in a table. The text is used for human readable output,
+// the value is used for structured output.
+type Cell struct {
+	Text  string
+	Value any
+}
+
+// Number is a decimal number kept in its exact textual representation, it is
+// written as a number rather than a string by structured formats.
+type Number string
+
+func (n Number) MarshalJSON() ([]byte, error) {
+	return []byte(n), nil
+}
+
+func (n Number) MarshalYAML() (any, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(n)}, nil
+}
+
+type Table struct {
+	Columns []Column
+	Rows    [][]Cell
+}
+
+func NewTable(columns ...Column) *Table {
+	return &Table{Columns: columns}
+}
+
+func (t *Table) AddRow(cells ...Cell) {
+	t.Rows = append(t.Rows, cells)
+}
+
+func ValidFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown output format %q, must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+func Write(w io.Writer, format string, t *Table) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, t)
+	case FormatJSON:
+		return writeJSON(w, t)
+	case FormatCSV:
+		return writeCSV(w, t)
+	case FormatYAML:
+		return writeYAML(w, t)
+	default:
+		return ValidFormat(format)
+	}
+}
+
+func writeTable(w io.Writer, t *Table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 8, ' ', 0)
+
+	titles := []string{}
+	for _, column := range t.Columns {
+		titles = append(titles, column.Title)
+	}
+	fmt.Fprintln(tw, strings.Join(titles, "\t"))
+
+	widths := make([]int, len(t.Columns))
+	for _, row := range t.Rows {
+		for j, cell := range row {
+			widths[j] = max(widths[j], utf8.RuneCountInString(cell.Text))
+		}
+	}
+
+	for _, row := range t.Rows {
+		texts := []string{}
+		for j, cell := range row {
+			text := cell.Text
+			if t.Columns[j].AlignRight {
+				text = fmt.Sprintf("%*s", widths[j], text)
+			}
+			texts = append(texts, text)
+		}
+		fmt.Fprintln(tw, strings.Join(texts, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, t *Table) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	for i, row := range t.Rows {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n  {"); err != nil {
+			return err
+		}
+
+		for j, cell := range row {
+			key, err := marshalJSON(t.Columns[j].Key)
+			if err != nil {
+				return err
+			}
+			value, err := marshalJSON(cell.Value)
+			if err != nil {
+				return err
+			}
+
+			separator := ","
+			if j == 0 {
+				separator = ""
+			}
+			if _, err := fmt.Fprintf(w, "%s\n    %s: %s", separator, key, value); err != nil {
+				return err
+			}
+		}
+
+		if _, err := io.WriteString(w, "\n  }"); err != nil {
+			return err
+		}
+	}
+
+	if len(t.Rows) > 0 {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]\n")
+
+	return err
+}
+
+func marshalJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+func writeCSV(w io.Writer, t *Table) error {
+	cw := csv.NewWriter(w)
+	if DecimalSeparator == "," {
+		cw.Comma = ';'
+	}
+
+	keys := []string{}
+	for _, column := range t.Columns {
+		keys = append(keys, column.Key)
+	}
+	if err := cw.Write(keys); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		values := []string{}
+		for _, cell := range row {
+			values = append(values, formatValue(cell.Value))
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func writeYAML(w io.Writer, t *Table) error {
+	document := &yaml.Node{Kind: yaml.SequenceNode}
+
+	for _, row := range t.Rows {
+		mapping := &yaml.Node{Kind: yaml.MappingNode}
+
+		for j, cell := range row {
+			value := &yaml.Node{}
+			if err := value.Encode(cell.Value); err != nil {
+				return err
+			}
+
+			mapping.Content = append(mapping.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: t.Columns[j].Key},
+				value,
+			)
+		}
+
+		document.Content = append(document.Content, mapping)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func formatValue(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case Number:
+		return strings.Replace(string(value), ".", DecimalSeparator, 1)
+	case float64:
+		return strings.Replace(strconv.FormatFloat(value, 'f', -1, 64), ".", DecimalSeparator, 1)
+	case []string:
+		return strings.Join(value, ",")
+	case map[string]string:
+		return FormatMap(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// FormatMap writes a map as comma separated key=value pairs sorted by key.
+func FormatMap(m map[string]string) string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+m[key])
+	}
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		separator string
+		want      string
+	}{
+		{
+			name:      "decimal point",
+			separator: ".",
+			want:      "name,cost,share\nShop; Berlin,1234.56,0.5\n\"EC2, spot\",-0.1,\n",
+		},
+		{
+			name:      "decimal comma",
+			separator: ",",
+			want:      "name;cost;share\n\"Shop; Berlin\";1234,56;0,5\nEC2, spot;-0,1;\n",
+		},
+	}
+
+	t.Cleanup(func() { DecimalSeparator = "." })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DecimalSeparator = tt.separator
+
+			table := NewTable(
+				Column{Key: "name", Title: "NAME"},
+				Column{Key: "cost", Title: "COST", AlignRight: true},
+				Column{Key: "share", Title: "SHARE", AlignRight: true},
+			)
+			table.AddRow(Cell{Text: "Shop; Berlin", Value: "Shop; Berlin"}, Cell{Text: "1.234,56 €", Value: Number("1234.56")}, Cell{Text: "50%", Value: 0.5})
+			table.AddRow(Cell{Text: "EC2, spot", Value: "EC2, spot"}, Cell{Text: "-0,10 €", Value: Number("-0.1")}, Cell{Text: "-"})
+
+			var b bytes.Buffer
+			if err := Write(&b, FormatCSV, table); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("Write() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}